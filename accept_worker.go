@@ -0,0 +1,71 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"log"
+
+	. "github.com/gotoolkits/lightmon/event"
+
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 accept inetCskAcceptSrc.c -- -Iheaders/
+
+func setupBpfAcceptWorkers() {
+	if !Kprobe_Runtime_Verifier("inet_csk_accept") {
+		log.Fatalln("inet_csk_accept is not available for kprobes")
+	}
+	if !haveKernelTypes() {
+		log.Fatalf("accept events need kernel BTF, %s not found, use -btf to load it from a file", KERNEL_BTF)
+	}
+
+	// Load pre-compiled programs and maps into the kernel.
+	objs := acceptObjects{}
+	if err := loadAcceptObjects(&objs, collectionOptions()); err != nil {
+		log.Fatalf("loading accept objects: %v", err)
+	}
+	addCloser(&objs)
+	loadKernelExclusions(objs.ExcludedPorts, objs.ExcludedNets)
+
+	kp, err := link.Kretprobe("inet_csk_accept", objs.InetCskAcceptRet, nil)
+	if err != nil {
+		log.Fatalf("attaching kretprobe inet_csk_accept: %s", err)
+	}
+	addCloser(kp)
+
+	rd := newPerfReader("accept", objs.AcceptEvents)
+
+	go (func() {
+		for {
+			if !readAcceptEvents(rd) {
+				return
+			}
+		}
+	})()
+}
+
+func readAcceptEvents(rd *perf.Reader) bool {
+	var event TcpEvent
+	sample, ok := readPerfSample(rd, "accept")
+	if !ok {
+		return false
+	}
+	if sample == nil || !decodeSample("accept", sample, &event) {
+		return true
+	}
+
+	printEvent(newAcceptEventPayload(&event))
+	return true
+}
+
+// newAcceptEventPayload builds the payload of an accepted connection. SrcIP
+// and SrcPort are the remote peer, DestIP and DestPort the local socket.
+func newAcceptEventPayload(event *TcpEvent) EventPayload {
+	eventPayload := newTcpEventPayload(event)
+	eventPayload.Type = TypeAccept
+	eventPayload.Direction = DirectionInbound
+	return eventPayload
+}
@@ -0,0 +1,248 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gotoolkits/lightmon/stats"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/perf"
+	"github.com/cilium/ebpf/ringbuf"
+)
+
+// lag policies, what happens when userspace does not keep up with the
+// buffers, see -lag_policy
+const (
+	// lagDropNew lets the kernel drop new events while a buffer is full.
+	lagDropNew = "drop_new"
+	// lagDropOld discards queued records in userspace while a buffer is
+	// more than half full, so the newest events are kept.
+	lagDropOld = "drop_old"
+	// lagExit stops lightmon as soon as the kernel drops an event.
+	lagExit = "exit"
+)
+
+// bufferPollInterval is how often the kernel drop counters are read and, with
+// -buffer_watermark, the readers are flushed. It bounds the delay of events
+// waiting below the watermark.
+const bufferPollInterval = time.Second
+
+var lostEvents = stats.NewLostCounter()
+
+var (
+	buffersMu sync.Mutex
+	// flushers are the readers flushed every bufferPollInterval.
+	flushers []interface{ Flush() error }
+	// kernelDrops are the per CPU counters of events the programs could
+	// not submit.
+	kernelDrops []kernelDrop
+)
+
+// kernelDrop is a per CPU array with a single counter of the events a
+// program of source lost for reason.
+type kernelDrop struct {
+	source string
+	reason string
+	m      *ebpf.Map
+}
+
+// checkBufferConfig validates the buffer options before any program is loaded.
+func checkBufferConfig() {
+	size := config.RingBufferSize
+	if size < os.Getpagesize() || size&(size-1) != 0 {
+		log.Fatalf("ringBufferSize %d must be a power of 2 and at least the page size", size)
+	}
+	if config.PerfBufferPages < 1 {
+		log.Fatalf("perfBufferPages %d must be at least 1", config.PerfBufferPages)
+	}
+	if config.BufferWatermark < 0 {
+		log.Fatalf("bufferWatermark %d must not be negative", config.BufferWatermark)
+	}
+	switch config.LagPolicy {
+	case lagDropNew, lagDropOld, lagExit:
+	default:
+		log.Fatalf("unknown lagPolicy %q, use %s, %s or %s", config.LagPolicy, lagDropNew, lagDropOld, lagExit)
+	}
+}
+
+// setRingbufSpec sizes the ring buffer map of spec and sets the wakeup
+// watermark of the programs writing to it. It must be called before the
+// spec is loaded.
+func setRingbufSpec(spec *ebpf.CollectionSpec, name string) {
+	spec.Maps[name].MaxEntries = uint32(config.RingBufferSize)
+	if err := spec.Variables["ringbuf_wakeup_bytes"].Set(uint64(config.BufferWatermark)); err != nil {
+		log.Fatalf("setting ring buffer watermark: %v", err)
+	}
+}
+
+func newPerfReader(source string, m *ebpf.Map) *perf.Reader {
+	opts := perf.ReaderOptions{Watermark: config.BufferWatermark}
+	rd, err := perf.NewReaderWithOptions(m, config.PerfBufferPages*os.Getpagesize(), opts)
+	if err != nil {
+		log.Fatalf("creating perf event reader for %s: %s", source, err)
+	}
+	addCloser(rd)
+	if config.BufferWatermark > 0 {
+		addFlusher(rd)
+	}
+	return rd
+}
+
+// newRingbufReader opens a reader on the ring buffer m. drops is the per CPU
+// array the programs count the events they could not reserve in.
+func newRingbufReader(source string, m *ebpf.Map, drops *ebpf.Map) *ringbuf.Reader {
+	rb, err := ringbuf.NewReader(m)
+	if err != nil {
+		log.Fatalf("creating ringbuf reader for %s: %s", source, err)
+	}
+	addCloser(rb)
+	if config.BufferWatermark > 0 {
+		addFlusher(rb)
+	}
+
+	addKernelDrops(source, stats.ReasonRingbufFull, drops)
+	return rb
+}
+
+func addKernelDrops(source, reason string, m *ebpf.Map) {
+	buffersMu.Lock()
+	defer buffersMu.Unlock()
+	kernelDrops = append(kernelDrops, kernelDrop{source, reason, m})
+}
+
+func addFlusher(f interface{ Flush() error }) {
+	buffersMu.Lock()
+	defer buffersMu.Unlock()
+	flushers = append(flushers, f)
+}
+
+// readPerfSample reads the next sample from rd. It returns false once rd is
+// closed, and a nil sample for lost records, flushes and records discarded
+// by the lag policy.
+func readPerfSample(rd *perf.Reader, source string) ([]byte, bool) {
+	record, err := rd.Read()
+	if err != nil {
+		if errors.Is(err, perf.ErrClosed) {
+			return nil, false
+		}
+		if !errors.Is(err, perf.ErrFlushed) {
+			log.Printf("reading from perf event reader: %s", err)
+		}
+		return nil, true
+	}
+
+	if record.LostSamples != 0 {
+		addLostEvents(source, stats.ReasonPerfOverflow, record.CPU, record.LostSamples)
+		return nil, true
+	}
+	if lagging(record.Remaining, rd.BufferSize()) {
+		addLostEvents(source, stats.ReasonLag, record.CPU, 1)
+		return nil, true
+	}
+	return record.RawSample, true
+}
+
+// readRingbufSample is readPerfSample for ring buffers. Events the programs
+// could not reserve are counted by pollKernelDrops.
+func readRingbufSample(rb *ringbuf.Reader, source string) ([]byte, bool) {
+	record, err := rb.Read()
+	if err != nil {
+		if errors.Is(err, ringbuf.ErrClosed) {
+			return nil, false
+		}
+		if !errors.Is(err, ringbuf.ErrFlushed) {
+			log.Printf("reading from ringbuf reader: %s", err)
+		}
+		return nil, true
+	}
+
+	if lagging(record.Remaining, rb.BufferSize()) {
+		addLostEvents(source, stats.ReasonLag, -1, 1)
+		return nil, true
+	}
+	return record.RawSample, true
+}
+
+// decodeSample parses a sample into v, counting the samples that cannot be
+// parsed.
+func decodeSample(source string, sample []byte, v interface{}) bool {
+	if err := binary.Read(bytes.NewBuffer(sample), binary.LittleEndian, v); err != nil {
+		log.Printf("parsing %s event: %s", source, err)
+		addLostEvents(source, stats.ReasonDecode, -1, 1)
+		return false
+	}
+	return true
+}
+
+// lagging reports whether a record should be discarded by the drop_old lag
+// policy, given the bytes left unread in its buffer.
+func lagging(remaining, size int) bool {
+	return config.LagPolicy == lagDropOld && remaining > size/2
+}
+
+func addLostEvents(source, reason string, cpu int, n uint64) {
+	lostEvents.Add(source, reason, cpu, n)
+	if config.LagPolicy == lagExit && reason == stats.ReasonPerfOverflow {
+		log.Fatalf("%s lost %d events on cpu %d, exiting as lagPolicy is %s", source, n, cpu, lagExit)
+	}
+}
+
+// pollKernelDrops copies the per CPU drop counters of the programs into
+// lostEvents.
+func pollKernelDrops() {
+	buffersMu.Lock()
+	defer buffersMu.Unlock()
+
+	for _, d := range kernelDrops {
+		var perCPU []uint64
+		if err := d.m.Lookup(uint32(0), &perCPU); err != nil {
+			log.Printf("reading %s drop counters: %v", d.source, err)
+			continue
+		}
+		for cpu, n := range perCPU {
+			if n == 0 {
+				continue
+			}
+			lostEvents.Set(d.source, d.reason, cpu, n)
+			if config.LagPolicy == lagExit {
+				log.Fatalf("%s lost %d events on cpu %d, exiting as lagPolicy is %s", d.source, n, cpu, lagExit)
+			}
+		}
+	}
+}
+
+// watchBuffers reads the kernel drop counters and flushes the readers every
+// bufferPollInterval, and logs the lost events every -lost_report_interval
+// seconds.
+func watchBuffers() {
+	report := time.Duration(config.LostReportInterval) * time.Second
+	lastReport := time.Now()
+
+	ticker := time.NewTicker(bufferPollInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		pollKernelDrops()
+
+		buffersMu.Lock()
+		for _, f := range flushers {
+			f.Flush()
+		}
+		buffersMu.Unlock()
+
+		if report > 0 && now.Sub(lastReport) >= report {
+			for _, line := range lostEvents.Report() {
+				log.Printf("lost events: %s", line)
+			}
+			lastReport = now
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package conv
+
+import "strconv"
+
+// ToErrno converts a kernel return code to a positive errno number
+// e.g. -111 to 111, 0 to 0
+func ToErrno(ret int) int32 {
+	if ret < 0 {
+		return int32(-ret)
+	}
+	return 0
+}
+
+// ToResult converts a kernel return code to a string
+// e.g. -111 to ECONNREFUSED, 0 to OK
+func ToResult(ret int) string {
+	if ret >= 0 {
+		return "OK"
+	}
+	if name, ok := errnoNames[-ret]; ok {
+		return name
+	}
+	return strconv.Itoa(-ret)
+}
+
+var errnoNames = map[int]string{
+	1:   "EPERM",
+	2:   "ENOENT",
+	4:   "EINTR",
+	9:   "EBADF",
+	11:  "EAGAIN",
+	12:  "ENOMEM",
+	13:  "EACCES",
+	14:  "EFAULT",
+	22:  "EINVAL",
+	24:  "EMFILE",
+	88:  "ENOTSOCK",
+	91:  "EPROTOTYPE",
+	93:  "EPROTONOSUPPORT",
+	95:  "EOPNOTSUPP",
+	97:  "EAFNOSUPPORT",
+	98:  "EADDRINUSE",
+	99:  "EADDRNOTAVAIL",
+	100: "ENETDOWN",
+	101: "ENETUNREACH",
+	103: "ECONNABORTED",
+	104: "ECONNRESET",
+	105: "ENOBUFS",
+	106: "EISCONN",
+	110: "ETIMEDOUT",
+	111: "ECONNREFUSED",
+	113: "EHOSTUNREACH",
+	114: "EALREADY",
+	115: "EINPROGRESS",
+}
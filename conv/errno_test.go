@@ -0,0 +1,37 @@
+package conv
+
+import (
+	"testing"
+)
+
+func TestSuccessResultConversion(t *testing.T) {
+	got := ToResult(0)
+	want := "OK"
+	if got != want {
+		t.Errorf("ToResult(0) = %s; want %s", got, want)
+	}
+}
+
+func TestExistingResultConversion(t *testing.T) {
+	got := ToResult(-111)
+	want := "ECONNREFUSED"
+	if got != want {
+		t.Errorf("ToResult(-111) = %s; want %s", got, want)
+	}
+}
+
+func TestNotExistingResultConversion(t *testing.T) {
+	got := ToResult(-200)
+	want := "200"
+	if got != want {
+		t.Errorf("ToResult(-200) = %s; want %s", got, want)
+	}
+}
+
+func TestErrnoConversion(t *testing.T) {
+	got := ToErrno(-115)
+	want := int32(115)
+	if got != want {
+		t.Errorf("ToErrno(-115) = %d; want %d", got, want)
+	}
+}
@@ -0,0 +1,27 @@
+package conv
+
+import "strconv"
+
+// ToTcpState converts a kernel TCP state number to a string
+// e.g. 1 to ESTABLISHED
+func ToTcpState(state int) string {
+	if name, ok := tcpStates[state]; ok {
+		return name
+	}
+	return strconv.Itoa(state)
+}
+
+var tcpStates = map[int]string{
+	1:  "ESTABLISHED",
+	2:  "SYN_SENT",
+	3:  "SYN_RECV",
+	4:  "FIN_WAIT1",
+	5:  "FIN_WAIT2",
+	6:  "TIME_WAIT",
+	7:  "CLOSE",
+	8:  "CLOSE_WAIT",
+	9:  "LAST_ACK",
+	10: "LISTEN",
+	11: "CLOSING",
+	12: "NEW_SYN_RECV",
+}
@@ -0,0 +1,21 @@
+package conv
+
+import (
+	"testing"
+)
+
+func TestExistingTcpStateConversion(t *testing.T) {
+	got := ToTcpState(1)
+	want := "ESTABLISHED"
+	if got != want {
+		t.Errorf("ToTcpState(1) = %s; want %s", got, want)
+	}
+}
+
+func TestNotExistingTcpStateConversion(t *testing.T) {
+	got := ToTcpState(42)
+	want := "42"
+	if got != want {
+		t.Errorf("ToTcpState(42) = %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,24 @@
+package conv
+
+import "strings"
+
+// ToUnixPath converts the sun_path of a sockaddr_un to a string, given the
+// length of the address without sun_family. Abstract socket names, which
+// start with a NUL byte, are returned with a leading "@" as in ss and
+// /proc/net/unix, e.g. "@/tmp/.X11-unix/X0".
+func ToUnixPath(path []byte, length uint32) string {
+	if int(length) > len(path) {
+		length = uint32(len(path))
+	}
+	path = path[:length]
+	if len(path) == 0 {
+		return ""
+	}
+	if path[0] == 0 {
+		return "@" + strings.ReplaceAll(string(path[1:]), "\x00", "@")
+	}
+	if i := strings.IndexByte(string(path), 0); i >= 0 {
+		path = path[:i]
+	}
+	return string(path)
+}
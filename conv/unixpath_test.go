@@ -0,0 +1,43 @@
+package conv
+
+import (
+	"testing"
+)
+
+func TestUnixPathConversion(t *testing.T) {
+	var path [108]byte
+	copy(path[:], "/var/run/docker.sock")
+	got := ToUnixPath(path[:], 21)
+	want := "/var/run/docker.sock"
+	if got != want {
+		t.Errorf("ToUnixPath(/var/run/docker.sock) = %s; want %s", got, want)
+	}
+}
+
+func TestUnixPathConversionWithoutTerminator(t *testing.T) {
+	var path [108]byte
+	copy(path[:], "/run/containerd/containerd.sock")
+	got := ToUnixPath(path[:], 108)
+	want := "/run/containerd/containerd.sock"
+	if got != want {
+		t.Errorf("ToUnixPath(/run/containerd/containerd.sock) = %s; want %s", got, want)
+	}
+}
+
+func TestAbstractUnixPathConversion(t *testing.T) {
+	var path [108]byte
+	copy(path[1:], "/tmp/.X11-unix/X0")
+	got := ToUnixPath(path[:], 18)
+	want := "@/tmp/.X11-unix/X0"
+	if got != want {
+		t.Errorf("ToUnixPath(\\0/tmp/.X11-unix/X0) = %s; want %s", got, want)
+	}
+}
+
+func TestEmptyUnixPathConversion(t *testing.T) {
+	var path [108]byte
+	got := ToUnixPath(path[:], 0)
+	if got != "" {
+		t.Errorf("ToUnixPath(\"\") = %s; want empty", got)
+	}
+}
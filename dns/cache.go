@@ -0,0 +1,142 @@
+package dns
+
+import (
+	"container/heap"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultMaxEntries bounds the cache when NewCache is given no limit.
+const defaultMaxEntries = 65536
+
+type cacheKey struct {
+	scope string
+	ip    string
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	name    string
+	expires time.Time
+	index   int // in Cache.byExpiry
+}
+
+// expiryHeap orders the entries by expiry, the first one expires next.
+type expiryHeap []*cacheEntry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expires.Before(h[j].expires) }
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	e := x.(*cacheEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+// Cache maps IP addresses to the domain names they were resolved from.
+// Entries expire with the TTL of the record and are kept per scope, e.g. per
+// container, so a name resolved in one container never annotates another.
+// When full, the entry closest to expiry makes room for new ones.
+type Cache struct {
+	mu         sync.Mutex
+	entries    map[cacheKey]*cacheEntry
+	byExpiry   expiryHeap
+	maxEntries int
+	now        func() time.Time
+}
+
+// NewCache creates a Cache holding at most maxEntries addresses.
+func NewCache(maxEntries int) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxEntries
+	}
+	return &Cache{
+		entries:    make(map[cacheKey]*cacheEntry),
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+// Add records that ip was resolved from name in scope for ttl.
+// Records with a zero TTL must not be cached and are ignored.
+func (c *Cache) Add(scope string, ip net.IP, name string, ttl time.Duration) {
+	if ttl <= 0 || ip == nil {
+		return
+	}
+	now := c.now()
+	key := cacheKey{scope, ip.String()}
+
+	name = strings.TrimSuffix(name, ".")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.name = name
+		e.expires = now.Add(ttl)
+		heap.Fix(&c.byExpiry, e.index)
+		return
+	}
+	if len(c.entries) >= c.maxEntries {
+		c.remove(c.byExpiry[0])
+	}
+	e := &cacheEntry{key: key, name: name, expires: now.Add(ttl)}
+	c.entries[key] = e
+	heap.Push(&c.byExpiry, e)
+}
+
+// AddResponse records every address of a DNS response under the queried
+// name, so CNAME chains are reported with the name the process asked for.
+func (c *Cache) AddResponse(scope string, resp Response) {
+	for _, a := range resp.Answers {
+		c.Add(scope, a.IP, resp.Question.Name, a.TTL)
+	}
+}
+
+// Lookup returns the name ip was resolved from in scope, or "" if it is
+// unknown or expired.
+func (c *Cache) Lookup(scope string, ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	key := cacheKey{scope, ip.String()}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return ""
+	}
+	if !c.now().Before(e.expires) {
+		c.remove(e)
+		return ""
+	}
+	return e.name
+}
+
+// Len returns the number of cached addresses, including expired ones that
+// have not been evicted yet.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *Cache) remove(e *cacheEntry) {
+	heap.Remove(&c.byExpiry, e.index)
+	delete(c.entries, e.key)
+}
@@ -0,0 +1,95 @@
+package dns
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestCache(maxEntries int) (*Cache, *time.Time) {
+	now := time.Date(2025, 4, 17, 14, 0, 0, 0, time.UTC)
+	c := NewCache(maxEntries)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestCache_Lookup(t *testing.T) {
+	c, now := newTestCache(0)
+	ip := net.ParseIP("10.0.0.1")
+	c.Add("web", ip, "db.internal.corp.", 30*time.Second)
+
+	if got := c.Lookup("web", ip); got != "db.internal.corp" {
+		t.Errorf("Lookup() = %q, want %q", got, "db.internal.corp")
+	}
+	if got := c.Lookup("", ip); got != "" {
+		t.Errorf("Lookup() from another scope = %q, want empty", got)
+	}
+	if got := c.Lookup("web", net.ParseIP("10.0.0.2")); got != "" {
+		t.Errorf("Lookup() of unknown ip = %q, want empty", got)
+	}
+
+	*now = now.Add(30 * time.Second)
+	if got := c.Lookup("web", ip); got != "" {
+		t.Errorf("Lookup() after ttl = %q, want empty", got)
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d, want expired entry removed", c.Len())
+	}
+}
+
+func TestCache_AddZeroTTL(t *testing.T) {
+	c, _ := newTestCache(0)
+	c.Add("", net.ParseIP("10.0.0.1"), "example.com", 0)
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d, want zero ttl records ignored", c.Len())
+	}
+}
+
+func TestCache_AddResponse(t *testing.T) {
+	c, _ := newTestCache(0)
+	resp, err := ParseResponse(exampleResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AddResponse("", resp)
+
+	for _, ip := range []string{"93.184.216.34", "2606:2800:220:1::1"} {
+		if got := c.Lookup("", net.ParseIP(ip)); got != "www.example.com" {
+			t.Errorf("Lookup(%s) = %q, want the queried name", ip, got)
+		}
+	}
+}
+
+func TestCache_MaxEntries(t *testing.T) {
+	c, now := newTestCache(2)
+	c.Add("", net.ParseIP("10.0.0.1"), "a.example.com", time.Second)
+	c.Add("", net.ParseIP("10.0.0.2"), "b.example.com", time.Hour)
+	c.Add("", net.ParseIP("10.0.0.3"), "c.example.com", time.Minute)
+
+	// the entry closest to expiry makes room
+	if got := c.Lookup("", net.ParseIP("10.0.0.3")); got != "c.example.com" {
+		t.Errorf("Lookup() = %q, want %q", got, "c.example.com")
+	}
+	if got := c.Lookup("", net.ParseIP("10.0.0.1")); got != "" {
+		t.Errorf("Lookup() = %q, want entry closest to expiry evicted", got)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+
+	// a renewed entry moves back
+	c.Add("", net.ParseIP("10.0.0.3"), "c.example.com", 2*time.Hour)
+	c.Add("", net.ParseIP("10.0.0.4"), "d.example.com", time.Minute)
+	if got := c.Lookup("", net.ParseIP("10.0.0.2")); got != "" {
+		t.Errorf("Lookup() = %q, want renewed entry kept over b.example.com", got)
+	}
+
+	// expired entries are removed on lookup
+	*now = now.Add(time.Minute)
+	if got := c.Lookup("", net.ParseIP("10.0.0.4")); got != "" {
+		t.Errorf("Lookup() = %q, want expired entry", got)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", c.Len())
+	}
+}
@@ -0,0 +1,201 @@
+// Package dns parses the parts of DNS messages that lightmon reports.
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const headerLen = 12
+
+var (
+	ErrShortMessage = errors.New("dns: message too short")
+	ErrNotQuery     = errors.New("dns: message is not a query")
+	ErrNotResponse  = errors.New("dns: message is not a response")
+	ErrNoQuestion   = errors.New("dns: message has no question")
+	ErrBadName      = errors.New("dns: malformed name")
+)
+
+// Question is the first entry of the question section of a DNS message.
+type Question struct {
+	ID    uint16
+	Name  string
+	Type  uint16
+	Class uint16
+}
+
+// ParseQuery parses the header and the first question of a DNS query.
+// Payloads captured in the kernel may be truncated, so anything after the
+// first question is ignored.
+func ParseQuery(msg []byte) (Question, error) {
+	if len(msg) < headerLen {
+		return Question{}, ErrShortMessage
+	}
+	// QR bit set means response.
+	if msg[2]&0x80 != 0 {
+		return Question{}, ErrNotQuery
+	}
+	if binary.BigEndian.Uint16(msg[4:6]) == 0 {
+		return Question{}, ErrNoQuestion
+	}
+
+	name, off, err := readName(msg, headerLen)
+	if err != nil {
+		return Question{}, err
+	}
+	if off+4 > len(msg) {
+		return Question{}, ErrShortMessage
+	}
+
+	return Question{
+		ID:    binary.BigEndian.Uint16(msg[0:2]),
+		Name:  name,
+		Type:  binary.BigEndian.Uint16(msg[off : off+2]),
+		Class: binary.BigEndian.Uint16(msg[off+2 : off+4]),
+	}, nil
+}
+
+// Answer is an A or AAAA record from the answer section of a DNS response.
+type Answer struct {
+	Name string
+	IP   net.IP
+	TTL  time.Duration
+}
+
+// Response is the question and the address records of a DNS response.
+type Response struct {
+	Question Question
+	Answers  []Answer
+}
+
+// ParseResponse parses the first question and the A/AAAA records of a DNS
+// response. Records other than A/AAAA, e.g. the CNAMEs leading to them, are
+// skipped. A truncated answer section yields the records parsed so far.
+func ParseResponse(msg []byte) (Response, error) {
+	if len(msg) < headerLen {
+		return Response{}, ErrShortMessage
+	}
+	if msg[2]&0x80 == 0 {
+		return Response{}, ErrNotResponse
+	}
+	if binary.BigEndian.Uint16(msg[4:6]) == 0 {
+		return Response{}, ErrNoQuestion
+	}
+	ancount := int(binary.BigEndian.Uint16(msg[6:8]))
+
+	qname, off, err := readName(msg, headerLen)
+	if err != nil {
+		return Response{}, err
+	}
+	if off+4 > len(msg) {
+		return Response{}, ErrShortMessage
+	}
+	resp := Response{
+		Question: Question{
+			ID:    binary.BigEndian.Uint16(msg[0:2]),
+			Name:  qname,
+			Type:  binary.BigEndian.Uint16(msg[off : off+2]),
+			Class: binary.BigEndian.Uint16(msg[off+2 : off+4]),
+		},
+	}
+	off += 4
+
+	// Additional questions are not used by real resolvers and not skipped.
+	for i := 0; i < ancount; i++ {
+		name, next, err := readName(msg, off)
+		if err != nil || next+10 > len(msg) {
+			break
+		}
+		rtype := binary.BigEndian.Uint16(msg[next : next+2])
+		ttl := binary.BigEndian.Uint32(msg[next+4 : next+8])
+		rdlen := int(binary.BigEndian.Uint16(msg[next+8 : next+10]))
+		rdata := next + 10
+		if rdata+rdlen > len(msg) {
+			break
+		}
+		if (rtype == 1 && rdlen == net.IPv4len) || (rtype == 28 && rdlen == net.IPv6len) {
+			resp.Answers = append(resp.Answers, Answer{
+				Name: name,
+				IP:   net.IP(append([]byte(nil), msg[rdata:rdata+rdlen]...)),
+				TTL:  time.Duration(ttl) * time.Second,
+			})
+		}
+		off = rdata + rdlen
+	}
+	return resp, nil
+}
+
+// readName reads a possibly compressed domain name starting at off and
+// returns it without the trailing dot, along with the offset right after it.
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	// Each pointer must go backwards, which bounds the number of jumps.
+	for jumps := 0; jumps <= len(msg); jumps++ {
+		if off >= len(msg) {
+			return "", 0, ErrShortMessage
+		}
+		l := int(msg[off])
+		switch l & 0xC0 {
+		case 0x00:
+			if l == 0 {
+				if end < 0 {
+					end = off + 1
+				}
+				if len(labels) == 0 {
+					return ".", end, nil
+				}
+				return strings.Join(labels, "."), end, nil
+			}
+			if off+1+l > len(msg) {
+				return "", 0, ErrShortMessage
+			}
+			labels = append(labels, string(msg[off+1:off+1+l]))
+			off += 1 + l
+		case 0xC0:
+			if off+2 > len(msg) {
+				return "", 0, ErrShortMessage
+			}
+			ptr := int(binary.BigEndian.Uint16(msg[off:off+2]) & 0x3FFF)
+			if ptr >= off {
+				return "", 0, ErrBadName
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = ptr
+		default:
+			return "", 0, ErrBadName
+		}
+	}
+	return "", 0, ErrBadName
+}
+
+var typeNames = map[uint16]string{
+	1:   "A",
+	2:   "NS",
+	5:   "CNAME",
+	6:   "SOA",
+	12:  "PTR",
+	15:  "MX",
+	16:  "TXT",
+	28:  "AAAA",
+	33:  "SRV",
+	35:  "NAPTR",
+	64:  "SVCB",
+	65:  "HTTPS",
+	255: "ANY",
+}
+
+// TypeString converts a query type to its mnemonic
+// e.g. 1 to A, 28 to AAAA, unknown types to TYPE<n>
+func TypeString(qtype uint16) string {
+	if name, ok := typeNames[qtype]; ok {
+		return name
+	}
+	return "TYPE" + strconv.Itoa(int(qtype))
+}
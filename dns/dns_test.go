@@ -0,0 +1,178 @@
+package dns
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// query for www.example.com A, id 0x1234, RD set
+var exampleQuery = []byte{
+	0x12, 0x34, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x03, 'w', 'w', 'w', 0x07, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0x03, 'c', 'o', 'm', 0x00,
+	0x00, 0x01, 0x00, 0x01,
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     []byte
+		want    Question
+		wantErr error
+	}{
+		{
+			name: "A query",
+			msg:  exampleQuery,
+			want: Question{ID: 0x1234, Name: "www.example.com", Type: 1, Class: 1},
+		},
+		{
+			name: "AAAA query with trailing garbage",
+			msg: append(append([]byte{}, exampleQuery[:len(exampleQuery)-4]...),
+				0x00, 0x1c, 0x00, 0x01, 0xde, 0xad),
+			want: Question{ID: 0x1234, Name: "www.example.com", Type: 28, Class: 1},
+		},
+		{
+			name: "root name",
+			msg: []byte{
+				0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x02, 0x00, 0x01,
+			},
+			want: Question{ID: 1, Name: ".", Type: 2, Class: 1},
+		},
+		{
+			name:    "short header",
+			msg:     exampleQuery[:8],
+			wantErr: ErrShortMessage,
+		},
+		{
+			name:    "truncated name",
+			msg:     exampleQuery[:20],
+			wantErr: ErrShortMessage,
+		},
+		{
+			name:    "truncated type",
+			msg:     exampleQuery[:len(exampleQuery)-2],
+			wantErr: ErrShortMessage,
+		},
+		{
+			name:    "response",
+			msg:     append([]byte{0x12, 0x34, 0x81, 0x80}, exampleQuery[4:]...),
+			wantErr: ErrNotQuery,
+		},
+		{
+			name: "no question",
+			msg: []byte{
+				0x12, 0x34, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+			wantErr: ErrNoQuestion,
+		},
+		{
+			name: "pointer loop",
+			msg: []byte{
+				0x12, 0x34, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0xc0, 0x0c, 0x00, 0x01, 0x00, 0x01,
+			},
+			wantErr: ErrBadName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuery(tt.msg)
+			if err != tt.wantErr {
+				t.Fatalf("ParseQuery() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypeString(t *testing.T) {
+	tests := []struct {
+		qtype uint16
+		want  string
+	}{
+		{1, "A"},
+		{28, "AAAA"},
+		{65, "HTTPS"},
+		{999, "TYPE999"},
+	}
+
+	for _, tt := range tests {
+		if got := TypeString(tt.qtype); got != tt.want {
+			t.Errorf("TypeString(%d) = %s; want %s", tt.qtype, got, tt.want)
+		}
+	}
+}
+
+// response to exampleQuery: www.example.com CNAME edge.example.com,
+// edge.example.com A 93.184.216.34 and an AAAA record, names compressed
+var exampleResponse = append(append([]byte{
+	0x12, 0x34, 0x81, 0x80, 0x00, 0x01, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00,
+}, exampleQuery[12:]...),
+	// www.example.com CNAME edge.example.com, ttl 300
+	0xc0, 0x0c, 0x00, 0x05, 0x00, 0x01, 0x00, 0x00, 0x01, 0x2c, 0x00, 0x07,
+	0x04, 'e', 'd', 'g', 'e', 0xc0, 0x10,
+	// edge.example.com A 93.184.216.34, ttl 60
+	0xc0, 0x2d, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x3c, 0x00, 0x04,
+	93, 184, 216, 34,
+	// edge.example.com AAAA 2606:2800:220:1::1, ttl 30
+	0xc0, 0x2d, 0x00, 0x1c, 0x00, 0x01, 0x00, 0x00, 0x00, 0x1e, 0x00, 0x10,
+	0x26, 0x06, 0x28, 0x00, 0x02, 0x20, 0x00, 0x01, 0, 0, 0, 0, 0, 0, 0, 0x01,
+)
+
+func TestParseResponse(t *testing.T) {
+	resp, err := ParseResponse(exampleResponse)
+	if err != nil {
+		t.Fatalf("ParseResponse() error = %v", err)
+	}
+	if resp.Question.Name != "www.example.com" || resp.Question.Type != 1 {
+		t.Errorf("ParseResponse() question = %+v", resp.Question)
+	}
+
+	want := []Answer{
+		{Name: "edge.example.com", IP: net.ParseIP("93.184.216.34"), TTL: 60 * time.Second},
+		{Name: "edge.example.com", IP: net.ParseIP("2606:2800:220:1::1"), TTL: 30 * time.Second},
+	}
+	if len(resp.Answers) != len(want) {
+		t.Fatalf("ParseResponse() answers = %+v, want %+v", resp.Answers, want)
+	}
+	for i, a := range resp.Answers {
+		if a.Name != want[i].Name || !a.IP.Equal(want[i].IP) || a.TTL != want[i].TTL {
+			t.Errorf("ParseResponse() answer %d = %+v, want %+v", i, a, want[i])
+		}
+	}
+}
+
+func TestParseResponseTruncated(t *testing.T) {
+	// cut in the middle of the AAAA record
+	resp, err := ParseResponse(exampleResponse[:len(exampleResponse)-8])
+	if err != nil {
+		t.Fatalf("ParseResponse() error = %v", err)
+	}
+	if len(resp.Answers) != 1 {
+		t.Errorf("ParseResponse() answers = %+v, want only the A record", resp.Answers)
+	}
+}
+
+func TestParseResponseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     []byte
+		wantErr error
+	}{
+		{"query", exampleQuery, ErrNotResponse},
+		{"short header", exampleResponse[:6], ErrShortMessage},
+		{"truncated question", exampleResponse[:20], ErrShortMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseResponse(tt.msg); err != tt.wantErr {
+				t.Errorf("ParseResponse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+// +build ignore
+
+#include "common.h"
+#include "vmlinux_core.h"
+
+// common.h only declares the x86 registers.
+#if defined(__TARGET_ARCH_arm64)
+#include "vmlinux_compact_arm64.h"
+#endif
+
+#include "bpf_tracing.h"
+#include "bpf_endian.h"
+#include "bpf_core_read.h"
+#include "exclude.h"
+#define TASK_IDS_CORE
+#include "task_ids.h"
+
+#define TASK_COMM_LEN 16
+#define AF_INET 2
+#define AF_INET6 10
+#define DNS_PORT 53
+#define DNS_MAX_LEN 512
+
+char LICENSE[] SEC("license") = "Dual MIT/GPL";
+
+/**
+ * Queries are copied by the udp_sendmsg kprobe from msg->msg_iter once it
+ * knows the destination port, and the socket is remembered in dns_socks.
+ * The iov_iter layout changed several times, so the program is built with
+ * CO-RE and needs kernel BTF.
+ *
+ * For responses, the udp_recvmsg kprobe remembers the user buffer in
+ * receives if the socket is in dns_socks, and the kretprobe copies what was
+ * received into it. Every send and receive syscall ends up there, read()
+ * and write() on connected sockets included, as used by Go's resolver.
+ */
+struct dns_event {
+    u8 comm[TASK_COMM_LEN];
+    __u32 pid;
+    __u32 uid;
+    __u16 sport;
+    __u16 dport;
+    __u16 af;
+    __u16 pad;
+    __be32 saddr;
+    __be32 daddr;
+    __u64 ts_us;
+    __u8 saddr6[16];
+    __u8 daddr6[16];
+    struct task_ids ids;
+    __u32 len;
+    __u32 response;
+    __u8 payload[DNS_MAX_LEN];
+};
+
+struct io_args {
+    __u64 ptr;
+    __u64 len;
+    __u64 sk;
+};
+
+// Userspace ABI, stable on 64-bit architectures.
+struct user_iovec {
+    __u64 iov_base;
+    __u64 iov_len;
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+} dns_events SEC(".maps");
+
+// receives holds the user buffer of a receive on a DNS socket, keyed by
+// pid_tgid, until udp_recvmsg returns.
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, 10240);
+    __type(key, u64);
+    __type(value, struct io_args);
+} receives SEC(".maps");
+
+// dns_socks holds the address of every struct sock that sent a DNS query.
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, 10240);
+    __type(key, u64);
+    __type(value, u8);
+} dns_socks SEC(".maps");
+
+// struct dns_event is too large for the BPF stack.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, u32);
+    __type(value, struct dns_event);
+} dns_heap SEC(".maps");
+
+static __always_inline struct dns_event *new_dns_event(struct sock *sk) {
+    u32 zero = 0;
+    struct dns_event *ev = bpf_map_lookup_elem(&dns_heap, &zero);
+    if (!ev) {
+        return NULL;
+    }
+    __builtin_memset(ev, 0, sizeof(struct dns_event) - DNS_MAX_LEN);
+
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    ev->pid = pid_tgid >> 32;
+    ev->uid = (u32)bpf_get_current_uid_gid();
+    ev->ts_us = bpf_ktime_get_ns() / 1000;
+    bpf_get_current_comm(&ev->comm, TASK_COMM_LEN);
+    ev->ids = current_task_ids();
+    ev->af = BPF_CORE_READ(sk, __sk_common.skc_family);
+    ev->sport = BPF_CORE_READ(sk, __sk_common.skc_num);
+    ev->dport = bpf_ntohs(BPF_CORE_READ(sk, __sk_common.skc_dport));
+    if (ev->af == AF_INET) {
+        ev->saddr = BPF_CORE_READ(sk, __sk_common.skc_rcv_saddr);
+        ev->daddr = BPF_CORE_READ(sk, __sk_common.skc_daddr);
+    } else if (bpf_core_field_exists(sk->__sk_common.skc_v6_daddr)) {
+        BPF_CORE_READ_INTO(&ev->saddr6, sk, __sk_common.skc_v6_rcv_saddr.in6_u.u6_addr8);
+        BPF_CORE_READ_INTO(&ev->daddr6, sk, __sk_common.skc_v6_daddr.in6_u.u6_addr8);
+    }
+    return ev;
+}
+
+// iter_user_buf resolves the user buffer behind msg_iter before it is
+// consumed. DNS clients put the whole message in a single buffer, so only
+// the first iovec is used. Buffers of in-kernel senders fail to copy later.
+static __always_inline int iter_user_buf(struct iov_iter *iter, struct io_args *args) {
+    if (bpf_core_field_exists(iter->ubuf) && bpf_core_enum_value_exists(enum iter_type, ITER_UBUF) &&
+        BPF_CORE_READ(iter, iter_type) == bpf_core_enum_value(enum iter_type, ITER_UBUF)) {
+        args->ptr = (__u64)BPF_CORE_READ(iter, ubuf);
+        args->len = BPF_CORE_READ(iter, count);
+        return 0;
+    }
+
+    const void *iov;
+    if (bpf_core_field_exists(iter->__iov)) {
+        iov = BPF_CORE_READ(iter, __iov);
+    } else {
+        iov = BPF_CORE_READ(iter, iov);
+    }
+    // The iovec array itself was copied into the kernel.
+    struct user_iovec vec = {};
+    if (!iov || bpf_probe_read_kernel(&vec, sizeof(vec), iov) < 0) {
+        return -1;
+    }
+    args->ptr = vec.iov_base;
+    args->len = vec.iov_len;
+    return 0;
+}
+
+// copy_payload copies up to DNS_MAX_LEN bytes of the user buffer into ev.
+static __always_inline int copy_payload(struct dns_event *ev, struct io_args *args) {
+    __u32 len = args->len;
+    if (len > DNS_MAX_LEN) {
+        len = DNS_MAX_LEN;
+    }
+    if (len == 0 || bpf_probe_read_user(ev->payload, len, (void *)args->ptr) < 0) {
+        return -1;
+    }
+    ev->len = len;
+    return 0;
+}
+
+static __always_inline int trace_dns_sendmsg(struct pt_regs *ctx, struct sock *sk, struct msghdr *msg) {
+    u16 family = BPF_CORE_READ(sk, __sk_common.skc_family);
+    if (family != AF_INET && family != AF_INET6) {
+        return 0;
+    }
+
+    // Unconnected sockets pass the destination in msg_name, connected
+    // sockets leave it NULL and use the peer stored in the socket.
+    struct sockaddr_in6 sa = {};
+    void *name = BPF_CORE_READ(msg, msg_name);
+    if (name && bpf_probe_read_kernel(&sa, sizeof(sa), name) < 0) {
+        return 0;
+    }
+    if (name && sa.sin6_family != AF_INET && sa.sin6_family != AF_INET6) {
+        return 0;
+    }
+
+    __u16 dport = name ? bpf_ntohs(sa.sin6_port) : bpf_ntohs(BPF_CORE_READ(sk, __sk_common.skc_dport));
+    if (dport != DNS_PORT) {
+        return 0;
+    }
+
+    u64 skaddr = (u64)sk;
+    u8 one = 1;
+    bpf_map_update_elem(&dns_socks, &skaddr, &one, BPF_ANY);
+
+    struct io_args args = {};
+    if (iter_user_buf(&msg->msg_iter, &args) < 0) {
+        return 0;
+    }
+
+    struct dns_event *ev = new_dns_event(sk);
+    if (!ev) {
+        return 0;
+    }
+    if (name) {
+        ev->af = sa.sin6_family;
+        ev->dport = dport;
+        if (sa.sin6_family == AF_INET) {
+            ev->daddr = ((struct sockaddr_in *)&sa)->sin_addr.s_addr;
+            __builtin_memset(ev->daddr6, 0, 16);
+        } else {
+            __builtin_memcpy(ev->daddr6, sa.sin6_addr.in6_u.u6_addr8, 16);
+        }
+    }
+    // Responses are still captured for the host cache, only the query
+    // event is dropped.
+    if (is_excluded(ev->af, ev->daddr, ev->daddr6, ev->dport)) {
+        return 0;
+    }
+    if (copy_payload(ev, &args) < 0) {
+        return 0;
+    }
+
+    bpf_perf_event_output(ctx, &dns_events, BPF_F_CURRENT_CPU, ev, sizeof(*ev));
+    return 0;
+}
+
+SEC("kprobe/udp_sendmsg")
+int BPF_KPROBE(dns_udp_sendmsg, struct sock *sk, struct msghdr *msg) {
+    return trace_dns_sendmsg(ctx, sk, msg);
+}
+
+SEC("kprobe/udpv6_sendmsg")
+int BPF_KPROBE(dns_udpv6_sendmsg, struct sock *sk, struct msghdr *msg) {
+    return trace_dns_sendmsg(ctx, sk, msg);
+}
+
+static __always_inline int trace_dns_recvmsg(struct sock *sk, struct msghdr *msg) {
+    u64 skaddr = (u64)sk;
+    if (!bpf_map_lookup_elem(&dns_socks, &skaddr)) {
+        return 0;
+    }
+
+    struct io_args args = {};
+    if (iter_user_buf(&msg->msg_iter, &args) < 0) {
+        return 0;
+    }
+    args.sk = skaddr;
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    bpf_map_update_elem(&receives, &pid_tgid, &args, BPF_ANY);
+    return 0;
+}
+
+// trace_dns_response emits the response udp_recvmsg copied to the buffer
+// saved on entry. ret is the length received.
+static __always_inline int trace_dns_response(struct pt_regs *ctx, long ret) {
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    struct io_args *pargs = bpf_map_lookup_elem(&receives, &pid_tgid);
+    if (!pargs) {
+        return 0;
+    }
+    struct io_args args = *pargs;
+    bpf_map_delete_elem(&receives, &pid_tgid);
+
+    if (ret <= 0) {
+        return 0;
+    }
+    // With MSG_TRUNC the full length is returned.
+    if (ret < args.len) {
+        args.len = ret;
+    }
+
+    struct dns_event *ev = new_dns_event((struct sock *)args.sk);
+    if (!ev) {
+        return 0;
+    }
+    ev->response = 1;
+    if (copy_payload(ev, &args) < 0) {
+        return 0;
+    }
+
+    bpf_perf_event_output(ctx, &dns_events, BPF_F_CURRENT_CPU, ev, sizeof(*ev));
+    return 0;
+}
+
+SEC("kprobe/udp_recvmsg")
+int BPF_KPROBE(dns_udp_recvmsg, struct sock *sk, struct msghdr *msg) {
+    return trace_dns_recvmsg(sk, msg);
+}
+
+SEC("kprobe/udpv6_recvmsg")
+int BPF_KPROBE(dns_udpv6_recvmsg, struct sock *sk, struct msghdr *msg) {
+    return trace_dns_recvmsg(sk, msg);
+}
+
+SEC("kretprobe/udp_recvmsg")
+int BPF_KRETPROBE(dns_udp_recvmsg_exit, int ret) {
+    return trace_dns_response(ctx, ret);
+}
+
+SEC("kretprobe/udpv6_recvmsg")
+int BPF_KRETPROBE(dns_udpv6_recvmsg_exit, int ret) {
+    return trace_dns_response(ctx, ret);
+}
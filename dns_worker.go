@@ -0,0 +1,156 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gotoolkits/lightmon/dns"
+	"github.com/gotoolkits/lightmon/dockerinfo"
+	. "github.com/gotoolkits/lightmon/event"
+	"github.com/gotoolkits/lightmon/linux"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 dnsquery dnsQuerySrc.c -- -Iheaders/
+
+func setupBpfDnsWorkers() {
+	if !Kprobe_Runtime_Verifier("udp_sendmsg") {
+		log.Fatalln("udp_sendmsg is not available for kprobes")
+	}
+	if !haveKernelTypes() {
+		log.Fatalf("dns needs kernel BTF, %s not found, use -btf to load it from a file", KERNEL_BTF)
+	}
+
+	// Load pre-compiled programs and maps into the kernel.
+	objs := dnsqueryObjects{}
+	if err := loadDnsqueryObjects(&objs, collectionOptions()); err != nil {
+		log.Fatalf("loading dns objects: %v", err)
+	}
+	addCloser(&objs)
+	loadKernelExclusions(objs.ExcludedPorts, objs.ExcludedNets)
+
+	probes := []struct {
+		symbol string
+		prog   *ebpf.Program
+		ret    bool
+	}{
+		{"udp_sendmsg", objs.DnsUdpSendmsg, false},
+		{"udpv6_sendmsg", objs.DnsUdpv6Sendmsg, false},
+		{"udp_recvmsg", objs.DnsUdpRecvmsg, false},
+		{"udpv6_recvmsg", objs.DnsUdpv6Recvmsg, false},
+		{"udp_recvmsg", objs.DnsUdpRecvmsgExit, true},
+		{"udpv6_recvmsg", objs.DnsUdpv6RecvmsgExit, true},
+	}
+	for _, p := range probes {
+		attach := link.Kprobe
+		if p.ret {
+			attach = link.Kretprobe
+		}
+		kp, err := attach(p.symbol, p.prog, nil)
+		if err != nil {
+			log.Fatalf("attaching kprobe %s: %s", p.symbol, err)
+		}
+		addCloser(kp)
+	}
+
+	rd := newPerfReader("dns", objs.DnsEvents)
+
+	go (func() {
+		for {
+			if !readDnsEvents(rd) {
+				return
+			}
+		}
+	})()
+}
+
+func readDnsEvents(rd *perf.Reader) bool {
+	var event DnsEvent
+	sample, ok := readPerfSample(rd, "dns")
+	if !ok {
+		return false
+	}
+	if sample == nil || !decodeSample("dns", sample, &event) {
+		return true
+	}
+
+	if event.Response != 0 {
+		addDnsResponse(&event)
+		return true
+	}
+
+	if !config.Dns {
+		return true
+	}
+	eventPayload, err := newDnsEventPayload(&event)
+	if err != nil {
+		// Not every datagram sent to port 53 is a well-formed query.
+		return true
+	}
+	printEvent(eventPayload)
+	return true
+}
+
+// addDnsResponse feeds the addresses of a DNS response into hostCache,
+// scoped by the ID of the container of the process that received it.
+func addDnsResponse(event *DnsEvent) {
+	if hostCache == nil {
+		return
+	}
+	resp, err := dns.ParseResponse(event.Payload[:dnsPayloadLen(event)])
+	if err != nil {
+		return
+	}
+	// Same scope as the ContainerID of the events, "" for host processes
+	// and in host-only mode.
+	scope := ""
+	if containerRuntime != nil {
+		pidStr := strconv.Itoa(int(event.Pid))
+		if info := dockerinfo.LookupContainer(event.CgroupId, pidStr, linux.StartNsToTicks(event.StartNs)); info != nil {
+			scope = info.ID
+		}
+	}
+	hostCache.AddResponse(scope, resp)
+}
+
+func dnsPayloadLen(event *DnsEvent) int {
+	if int(event.Len) > len(event.Payload) {
+		return len(event.Payload)
+	}
+	return int(event.Len)
+}
+
+func newDnsEventPayload(event *DnsEvent) (EventPayload, error) {
+	q, err := dns.ParseQuery(event.Payload[:dnsPayloadLen(event)])
+	if err != nil {
+		return EventPayload{}, err
+	}
+
+	eventPayload := newTcpEventPayload(&TcpEvent{
+		Comm:   event.Comm,
+		Pid:    event.Pid,
+		Uid:    event.Uid,
+		Sport:  event.Sport,
+		Dport:  event.Dport,
+		Af:     event.Af,
+		Saddr:  event.Saddr,
+		Daddr:  event.Daddr,
+		TsUs:   event.TsUs,
+		Saddr6: event.Saddr6,
+		Daddr6: event.Daddr6,
+
+		TaskIDs: event.TaskIDs,
+	})
+	eventPayload.Type = TypeDNS
+	eventPayload.Protocol = ProtocolUDP
+	eventPayload.Result = ""
+	eventPayload.QName = q.Name
+	eventPayload.QType = dns.TypeString(q.Type)
+	return eventPayload, nil
+}
@@ -9,14 +9,19 @@ import (
 type LocalCaches struct {
    RefreshProccessCache  cache.ICache
    RefreshContainerCache cache.ICache
+   RefreshCgroupCache    cache.ICache
+   // 运行时未列出的容器 ID，如 sandbox 或其他运行时的容器
+   UnknownContainerCache cache.ICache
 }
 
 func InitLocalCaches() *LocalCaches{
 
 	rpc := cache.NewMemCache(cache.WithClearInterval(10*time.Minute))
 	rcc := cache.NewMemCache(cache.WithClearInterval(10*time.Minute))
+	rcg := cache.NewMemCache(cache.WithClearInterval(10*time.Minute))
+	ucc := cache.NewMemCache(cache.WithClearInterval(10*time.Minute))
 
 	return &LocalCaches{
-			rpc,rcc,
+			rpc,rcc,rcg,ucc,
 	}
 }
\ No newline at end of file
@@ -0,0 +1,161 @@
+package dockerinfo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ContainerdK8sNamespace 是 CRI 插件创建容器所用的 containerd 命名空间
+const ContainerdK8sNamespace = "k8s.io"
+
+// CRI 插件写入 OCI config.json 的注解
+const (
+	criContainerType    = "io.kubernetes.cri.container-type"
+	criContainerName    = "io.kubernetes.cri.container-name"
+	criSandboxID        = "io.kubernetes.cri.sandbox-id"
+	criSandboxName      = "io.kubernetes.cri.sandbox-name"
+	criSandboxNamespace = "io.kubernetes.cri.sandbox-namespace"
+
+	criTypeSandbox = "sandbox"
+)
+
+// ErrSandbox 表示该 task 是 Pod 的 sandbox（pause）容器
+var ErrSandbox = errors.New("pod sandbox")
+
+// ContainerdInfo 结构体用于读取 containerd 的容器信息
+type ContainerdInfo struct {
+	StateDir  string // io.containerd.runtime.v2.task 状态目录
+	Namespace string // containerd 命名空间
+}
+
+// NewContainerdInfoWithPath 创建ContainerdInfo实例
+func NewContainerdInfoWithPath(stateDir string, namespace string) *ContainerdInfo {
+	if stateDir == "" {
+		stateDir = "/run/containerd/io.containerd.runtime.v2.task" // 默认containerd task状态目录
+	}
+	if namespace == "" {
+		namespace = ContainerdK8sNamespace
+	}
+	return &ContainerdInfo{
+		StateDir:  stateDir,
+		Namespace: namespace,
+	}
+}
+
+// Name 实现 Runtime 接口
+func (c *ContainerdInfo) Name() string {
+	return RuntimeContainerd
+}
+
+// Detect 判断命名空间的 task 状态目录是否存在
+func (c *ContainerdInfo) Detect() bool {
+	return pathExists(filepath.Join(c.StateDir, c.Namespace))
+}
+
+// Containers 获取所有运行中容器的信息
+func (c *ContainerdInfo) Containers() ([]*ContainerInfo, error) {
+	return c.GetAllContainersInfo()
+}
+
+// WatchDirs 返回命名空间的 task 状态目录，init.pid 在其下一层
+func (c *ContainerdInfo) WatchDirs() []WatchDir {
+	return []WatchDir{{Path: filepath.Join(c.StateDir, c.Namespace), Depth: 1}}
+}
+
+// GetContainerIDs 获取命名空间下所有运行中的 task ID 列表，包括 sandbox
+func (c *ContainerdInfo) GetContainerIDs() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(c.StateDir, c.Namespace))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPath, err)
+	}
+
+	var containerIDs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			containerIDs = append(containerIDs, entry.Name())
+		}
+	}
+	return containerIDs, nil
+}
+
+// GetInitProcessPID 从 task 目录的 init.pid 文件中读取容器的初始进程ID
+func (c *ContainerdInfo) GetInitProcessPID(containerID string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(c.StateDir, c.Namespace, containerID, "init.pid"))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrReadFile, err)
+	}
+	pid := strings.TrimSpace(string(content))
+	if pid == "" {
+		return "", fmt.Errorf("%w: empty init.pid", ErrReadFile)
+	}
+	return pid, nil
+}
+
+// getAnnotations 读取 task bundle 中 OCI config.json 的注解
+func (c *ContainerdInfo) getAnnotations(containerID string) (map[string]string, error) {
+	return readAnnotations(filepath.Join(c.StateDir, c.Namespace, containerID, "config.json"))
+}
+
+// GetContainerInfo 获取指定容器ID的完整信息，sandbox 返回 ErrSandbox
+func (c *ContainerdInfo) GetContainerInfo(containerID string) (*ContainerInfo, error) {
+	annotations, err := c.getAnnotations(containerID)
+	if err != nil {
+		return nil, err
+	}
+	if annotations[criContainerType] == criTypeSandbox {
+		return nil, ErrSandbox
+	}
+
+	initPID, err := c.GetInitProcessPID(containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ContainerInfo{
+		ID:           containerID,
+		Name:         annotations[criContainerName],
+		InitPID:      initPID,
+		PodName:      annotations[criSandboxName],
+		PodNamespace: annotations[criSandboxNamespace],
+	}
+
+	// containerd 1.6 之前容器上只有 sandbox-id，Pod 信息在 sandbox 的注解中
+	if sandboxID := annotations[criSandboxID]; info.PodName == "" && sandboxID != "" {
+		sandbox, err := c.getAnnotations(sandboxID)
+		if err != nil {
+			fmt.Printf("获取容器 %s 的 sandbox %s 信息失败: %v\n", containerID, sandboxID, err)
+		} else {
+			info.PodName = sandbox[criSandboxName]
+			info.PodNamespace = sandbox[criSandboxNamespace]
+		}
+	}
+
+	return info, nil
+}
+
+// GetAllContainersInfo 获取所有运行中容器的信息，不包括 sandbox
+func (c *ContainerdInfo) GetAllContainersInfo() ([]*ContainerInfo, error) {
+	containerIDs, err := c.GetContainerIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var containersInfo []*ContainerInfo
+	for _, id := range containerIDs {
+		info, err := c.GetContainerInfo(id)
+		if errors.Is(err, ErrSandbox) {
+			continue
+		}
+		if err != nil {
+			// 记录错误但继续处理其他容器
+			fmt.Printf("获取容器 %s 信息失败: %v\n", id, err)
+			continue
+		}
+		containersInfo = append(containersInfo, info)
+	}
+
+	return containersInfo, nil
+}
@@ -0,0 +1,153 @@
+package dockerinfo
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// 创建模拟的 containerd task 目录
+func createTestContainerdTask(t *testing.T, stateDir, containerID string, initPID int, annotations map[string]string) {
+	taskDir := filepath.Join(stateDir, ContainerdK8sNamespace, containerID)
+	if err := os.MkdirAll(taskDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if initPID > 0 {
+		pidContent := []byte(strconv.Itoa(initPID))
+		if err := os.WriteFile(filepath.Join(taskDir, "init.pid"), pidContent, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, err := json.Marshal(map[string]interface{}{
+		"ociVersion":  "1.1.0",
+		"process":     map[string]interface{}{"args": []string{"/pause"}},
+		"annotations": annotations,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(taskDir, "config.json"), config, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 创建一个 Pod：sandbox 和一个业务容器
+func setupTestPod(t *testing.T) string {
+	stateDir := t.TempDir()
+
+	createTestContainerdTask(t, stateDir, "sandbox1", 1000, map[string]string{
+		criContainerType:    criTypeSandbox,
+		criSandboxName:      "nginx-7c5ddbdf54-x2x9q",
+		criSandboxNamespace: "web",
+	})
+	createTestContainerdTask(t, stateDir, "container1", 1001, map[string]string{
+		criContainerType:    "container",
+		criContainerName:    "nginx",
+		criSandboxID:        "sandbox1",
+		criSandboxName:      "nginx-7c5ddbdf54-x2x9q",
+		criSandboxNamespace: "web",
+	})
+	// containerd 1.6 之前的容器只有 sandbox-id
+	createTestContainerdTask(t, stateDir, "container2", 1002, map[string]string{
+		criContainerType: "container",
+		criContainerName: "sidecar",
+		criSandboxID:     "sandbox1",
+	})
+	return stateDir
+}
+
+// 测试NewContainerdInfoWithPath函数
+func TestNewContainerdInfo(t *testing.T) {
+	c := NewContainerdInfoWithPath("", "")
+	if c.StateDir != "/run/containerd/io.containerd.runtime.v2.task" {
+		t.Errorf("期望目录 /run/containerd/io.containerd.runtime.v2.task, 得到 %s", c.StateDir)
+	}
+	if c.Namespace != ContainerdK8sNamespace {
+		t.Errorf("期望命名空间 %s, 得到 %s", ContainerdK8sNamespace, c.Namespace)
+	}
+}
+
+// 测试ContainerdInfo.GetContainerInfo方法
+func TestContainerdGetContainerInfo(t *testing.T) {
+	c := NewContainerdInfoWithPath(setupTestPod(t), ContainerdK8sNamespace)
+
+	tests := []struct {
+		name        string
+		containerID string
+		want        ContainerInfo
+		wantErr     error
+	}{
+		{
+			name:        "容器注解",
+			containerID: "container1",
+			want:        ContainerInfo{ID: "container1", Name: "nginx", InitPID: "1001", PodName: "nginx-7c5ddbdf54-x2x9q", PodNamespace: "web"},
+		},
+		{
+			name:        "sandbox注解",
+			containerID: "container2",
+			want:        ContainerInfo{ID: "container2", Name: "sidecar", InitPID: "1002", PodName: "nginx-7c5ddbdf54-x2x9q", PodNamespace: "web"},
+		},
+		{
+			name:        "sandbox",
+			containerID: "sandbox1",
+			wantErr:     ErrSandbox,
+		},
+		{
+			name:        "容器不存在",
+			containerID: "nonexistent",
+			wantErr:     ErrReadFile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := c.GetContainerInfo(tt.containerID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("期望错误 %v, 得到 %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("未预期的错误: %v", err)
+			}
+			if *info != tt.want {
+				t.Errorf("期望 %+v, 得到 %+v", tt.want, *info)
+			}
+		})
+	}
+}
+
+// 测试ContainerdInfo.GetAllContainersInfo方法
+func TestContainerdGetAllContainersInfo(t *testing.T) {
+	stateDir := setupTestPod(t)
+	// 已创建但未启动的 task 没有 init.pid
+	createTestContainerdTask(t, stateDir, "container3", 0, map[string]string{
+		criContainerType: "container",
+		criContainerName: "init",
+		criSandboxID:     "sandbox1",
+	})
+
+	c := NewContainerdInfoWithPath(stateDir, ContainerdK8sNamespace)
+	infos, err := c.GetAllContainersInfo()
+	if err != nil {
+		t.Fatalf("获取所有容器信息失败: %v", err)
+	}
+
+	names := map[string]bool{}
+	for _, info := range infos {
+		names[info.Name] = true
+	}
+	if len(infos) != 2 || !names["nginx"] || !names["sidecar"] {
+		t.Errorf("期望容器 nginx 和 sidecar, 得到 %v", names)
+	}
+
+	if _, err := NewContainerdInfoWithPath(stateDir, "moby").GetAllContainersInfo(); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("期望错误 %v, 得到 %v", ErrInvalidPath, err)
+	}
+}
@@ -0,0 +1,105 @@
+package dockerinfo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CRI-O 写入 OCI config.json 的注解
+const (
+	crioContainerType = "io.kubernetes.cri-o.ContainerType"
+	crioContainerName = "io.kubernetes.container.name"
+	crioPodName       = "io.kubernetes.pod.name"
+	crioPodNamespace  = "io.kubernetes.pod.namespace"
+	crioTypeSandbox   = "sandbox"
+)
+
+// CrioInfo 结构体用于读取 CRI-O 的容器信息
+type CrioInfo struct {
+	RunDir  string           // CRI-O 运行时目录，存放 crio.sock
+	Storage ContainerStorage // CRI-O 使用的 containers/storage 目录
+}
+
+// NewCrioInfoWithPath 创建CrioInfo实例
+func NewCrioInfoWithPath(runDir string, storage ContainerStorage) *CrioInfo {
+	if runDir == "" {
+		runDir = "/var/run/crio" // 默认CRI-O运行时目录
+	}
+	if storage.GraphRoot == "" {
+		storage.GraphRoot = "/var/lib/containers/storage"
+	}
+	if storage.RunRoot == "" {
+		storage.RunRoot = "/run/containers/storage"
+	}
+	return &CrioInfo{
+		RunDir:  runDir,
+		Storage: storage,
+	}
+}
+
+// Name 实现 Runtime 接口
+func (c *CrioInfo) Name() string {
+	return RuntimeCrio
+}
+
+// Detect 判断CRI-O运行时目录和存储目录是否存在
+func (c *CrioInfo) Detect() bool {
+	return pathExists(c.RunDir) && pathExists(c.Storage.GraphRoot)
+}
+
+// WatchDirs 返回存储运行时目录中的容器目录
+func (c *CrioInfo) WatchDirs() []WatchDir {
+	return []WatchDir{c.Storage.watchDir()}
+}
+
+// GetContainerInfo 获取指定容器ID的完整信息，sandbox 返回 ErrSandbox
+func (c *CrioInfo) GetContainerInfo(containerID string) (*ContainerInfo, error) {
+	annotations, err := c.Storage.annotations(containerID)
+	if err != nil {
+		return nil, err
+	}
+	// 同一存储中由 Podman 等其他工具创建的容器
+	if annotations[crioContainerType] == "" || annotations[containerManager] == libpodManager {
+		return nil, errOtherRuntime
+	}
+	if annotations[crioContainerType] == crioTypeSandbox {
+		return nil, ErrSandbox
+	}
+
+	initPID, err := c.Storage.initProcessPID(containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ContainerInfo{
+		ID:           containerID,
+		Name:         annotations[crioContainerName],
+		InitPID:      initPID,
+		PodName:      annotations[crioPodName],
+		PodNamespace: annotations[crioPodNamespace],
+	}, nil
+}
+
+// Containers 获取所有运行中容器的信息，不包括 sandbox
+func (c *CrioInfo) Containers() ([]*ContainerInfo, error) {
+	containers, err := c.Storage.containers()
+	if err != nil {
+		return nil, err
+	}
+
+	var containersInfo []*ContainerInfo
+	for _, container := range containers {
+		info, err := c.GetContainerInfo(container.ID)
+		if errors.Is(err, ErrSandbox) || errors.Is(err, ErrNotRunning) || errors.Is(err, errOtherRuntime) {
+			continue
+		}
+		if err != nil {
+			// 记录错误但继续处理其他容器
+			fmt.Printf("获取容器 %s 信息失败: %v\n", container.ID, err)
+			continue
+		}
+		containersInfo = append(containersInfo, info)
+	}
+
+	return containersInfo, nil
+}
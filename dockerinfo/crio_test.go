@@ -0,0 +1,130 @@
+package dockerinfo
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// 创建模拟的 containers/storage 目录
+func setupTestStorage(t *testing.T) ContainerStorage {
+	root := t.TempDir()
+	storage := ContainerStorage{
+		GraphRoot: filepath.Join(root, "lib"),
+		RunRoot:   filepath.Join(root, "run"),
+	}
+	if err := os.MkdirAll(filepath.Join(storage.GraphRoot, "overlay-containers"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return storage
+}
+
+// 在存储中创建测试容器，initPID 为 0 表示容器未运行
+func createTestStorageContainer(t *testing.T, storage ContainerStorage, containerID, name string, initPID int, annotations map[string]string) {
+	userdata := filepath.Join(storage.GraphRoot, "overlay-containers", containerID, "userdata")
+	if err := os.MkdirAll(userdata, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config, err := json.Marshal(map[string]interface{}{"annotations": annotations})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(userdata, "config.json"), config, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if initPID > 0 {
+		runUserdata := filepath.Join(storage.RunRoot, "overlay-containers", containerID, "userdata")
+		if err := os.MkdirAll(runUserdata, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(runUserdata, "pidfile"), []byte(strconv.Itoa(initPID)+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 追加到 containers.json
+	listPath := filepath.Join(storage.GraphRoot, "overlay-containers", "containers.json")
+	var containers []storageContainer
+	if content, err := os.ReadFile(listPath); err == nil {
+		if err := json.Unmarshal(content, &containers); err != nil {
+			t.Fatal(err)
+		}
+	}
+	containers = append(containers, storageContainer{ID: containerID, Names: []string{name}})
+	content, err := json.Marshal(containers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(listPath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 测试CrioInfo.GetContainerInfo和Containers方法
+func TestCrioContainers(t *testing.T) {
+	storage := setupTestStorage(t)
+	pod := map[string]string{
+		crioPodName:      "router-default-6d8f4b7c9-jx2lp",
+		crioPodNamespace: "openshift-ingress",
+	}
+	withPod := func(annotations map[string]string) map[string]string {
+		for k, v := range pod {
+			annotations[k] = v
+		}
+		return annotations
+	}
+
+	createTestStorageContainer(t, storage, "sandbox1", "k8s_POD_router", 1000, withPod(map[string]string{
+		crioContainerType: crioTypeSandbox,
+	}))
+	createTestStorageContainer(t, storage, "container1", "k8s_router_router", 1001, withPod(map[string]string{
+		crioContainerType: "container",
+		crioContainerName: "router",
+	}))
+	createTestStorageContainer(t, storage, "stopped1", "k8s_router_old", 0, withPod(map[string]string{
+		crioContainerType: "container",
+		crioContainerName: "router",
+	}))
+	createTestStorageContainer(t, storage, "podman1", "web", 1003, map[string]string{
+		containerManager:  libpodManager,
+		crioContainerType: "container",
+	})
+
+	runDir := t.TempDir()
+	c := NewCrioInfoWithPath(runDir, storage)
+	if !c.Detect() {
+		t.Fatal("期望检测到CRI-O")
+	}
+
+	info, err := c.GetContainerInfo("container1")
+	if err != nil {
+		t.Fatalf("未预期的错误: %v", err)
+	}
+	want := ContainerInfo{ID: "container1", Name: "router", InitPID: "1001", PodName: pod[crioPodName], PodNamespace: pod[crioPodNamespace]}
+	if *info != want {
+		t.Errorf("期望 %+v, 得到 %+v", want, *info)
+	}
+
+	if _, err := c.GetContainerInfo("sandbox1"); !errors.Is(err, ErrSandbox) {
+		t.Errorf("期望错误 %v, 得到 %v", ErrSandbox, err)
+	}
+	if _, err := c.GetContainerInfo("stopped1"); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("期望错误 %v, 得到 %v", ErrNotRunning, err)
+	}
+
+	infos, err := c.Containers()
+	if err != nil {
+		t.Fatalf("获取所有容器信息失败: %v", err)
+	}
+	if len(infos) != 1 || infos[0].ID != "container1" {
+		t.Errorf("期望只有容器 container1, 得到 %d 个容器", len(infos))
+	}
+
+	if NewCrioInfoWithPath(filepath.Join(runDir, "nonexistent"), storage).Detect() {
+		t.Error("运行时目录不存在时期望检测失败")
+	}
+}
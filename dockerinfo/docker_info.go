@@ -29,10 +29,12 @@ type DockerInfo struct {
 
 // ContainerInfo 结构体用于存储单个容器的信息
 type ContainerInfo struct {
-	ID        string // 容器ID
-	Name      string // 容器名称
-	ParentPID string // 父进程ID（容器进程ID）
-	InitPID   string // 初始进程ID
+	ID           string // 容器ID
+	Name         string // 容器名称
+	InitPID      string // 初始进程ID
+	CgroupID     uint64 // 初始进程所在的 cgroup v2 ID，未知时为 0
+	PodName      string // Kubernetes Pod 名称，非 Kubernetes 容器为空
+	PodNamespace string // Kubernetes Pod 所在的命名空间
 }
 
 // NewDockerInfo 创建DockerInfo实例
@@ -49,6 +51,39 @@ func NewDockerInfoWithPath(dockerRuntimeDir string,dockerDataDir string) *Docker
 	}
 }
 
+// Name 实现 Runtime 接口
+func (d *DockerInfo) Name() string {
+	return RuntimeDocker
+}
+
+// Detect 判断Docker运行时目录和数据目录是否存在
+func (d *DockerInfo) Detect() bool {
+	return pathExists(d.DockerRootDir) && pathExists(d.DataDir)
+}
+
+// Containers 获取所有运行中容器的信息，去掉名称开头的 "/"
+func (d *DockerInfo) Containers() ([]*ContainerInfo, error) {
+	infos, err := d.GetAllContainersInfo()
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if len(info.Name) > 1 {
+			info.Name = info.Name[1:]
+		}
+	}
+	return infos, nil
+}
+
+// WatchDirs 返回每个运行中容器一个子目录的 containerd 目录，以及写入
+// state.json 的 runc 状态目录
+func (d *DockerInfo) WatchDirs() []WatchDir {
+	return []WatchDir{
+		{Path: filepath.Join(d.DockerRootDir, "containerd"), Depth: 0},
+		{Path: filepath.Join(d.DockerRootDir, "runtime-runc/moby"), Depth: 1},
+	}
+}
+
 // GetContainerIDs 获取所有运行中的容器ID列表
 func (d *DockerInfo) GetContainerIDs() ([]string, error) {
 	containerdPath := filepath.Join(d.DockerRootDir, "/containerd")
@@ -175,12 +210,6 @@ func (d *DockerInfo) GetContainerInfo(containerID string) (*ContainerInfo, error
 		return nil, err
 	}
 
-	// 获取父进程ID（容器进程ID）
-	parentPID, err := GetPPID(initPID)
-	if err != nil {
-		return nil, err
-	}
-
 	// 获取容器名称
 	name, err := d.GetContainerName(containerID)
 	if err != nil {
@@ -193,7 +222,6 @@ func (d *DockerInfo) GetContainerInfo(containerID string) (*ContainerInfo, error
 		ID:        containerID,
 		Name:      name,
 		InitPID:   initPID,
-		ParentPID: parentPID,
 	}, nil
 }
 
@@ -220,159 +248,14 @@ func (d *DockerInfo) GetAllContainersInfo() ([]*ContainerInfo, error) {
 }
 
 
-// GetPPID 获取指定进程ID的父进程ID
-func GetPPID(pid string) (string, error) {
-	statusPath := filepath.Join("/proc", pid, "status")
-	file, err := os.Open(statusPath)
-	if err != nil {
-		return "", fmt.Errorf("%w: %v", ErrProcessNotFound, err)
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "PPid:") {
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				return parts[1], nil
-			}
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return "", fmt.Errorf("%w: %v", ErrReadFile, err)
-	}
-
-	return "", ErrProcessNotFound
-}
-
-func GetRootParentPID(pid string) (string, error) {
-    currentPID := pid
-    visited := make(map[string]bool) 
-
-    for {
-        if visited[currentPID] {
-            return "", fmt.Errorf("detected a cycle in parent process hierarchy")
-        }
-        visited[currentPID] = true
-
-        ppid, err := GetPPID(currentPID)
-        if err != nil {
-            return "", fmt.Errorf("failed to get parent PID for %s: %w", currentPID, err)
-        }
-
-        if ppid == "1" {
-            return currentPID, nil
-        }
-        currentPID = ppid
-    }
-}
-
-func GetRootPrevParentPID(pid string) (string, error) {
-    currentPID := pid
-    visited := make(map[string]bool) 
-	prevPID := ""
-
-    for {
-        if visited[currentPID] {
-            return "", fmt.Errorf("detected a cycle in parent process hierarchy")
-        }
-        visited[currentPID] = true
-
-        ppid, err := GetPPID(currentPID)
-        if err != nil {
-            return "", fmt.Errorf("failed to get parent PID for %s: %w", currentPID, err)
-        }
-
-        if ppid == "1" {
-			if prevPID == "" {
-                return currentPID, nil
-            }
-            return prevPID, nil
-        }
-
-		prevPID = currentPID
-        currentPID = ppid
-    }
+// pathExists 判断路径是否存在
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
 
-// GetChildrenPIDs 获取指定进程ID的所有子进程ID（包括子子进程）
-func GetChildrenPIDs(pid string) ([]string, error) {
-	if _, err := os.Stat(filepath.Join("/proc", pid)); err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("%w: %v", ErrProcessNotFound, err)
-	}
-
-	childrenPIDs := make([]string, 0)
-	visited := make(map[string]bool) // 用于防止循环引用
-
-	// 递归获取子进程
-	var getChildren func(parentPID string) error
-	getChildren = func(parentPID string) error {
-		if visited[parentPID] {
-			return nil
-		}
-		visited[parentPID] = true
-
-
-		taskPath := filepath.Join("/proc", parentPID, "task")
-		tasks, err := os.ReadDir(taskPath)
-		if err != nil {
-			return fmt.Errorf("%w: %v", ErrProcessNotFound, err)
-		}
-
-		// 遍历每个task的children文件
-		for _, task := range tasks {
-			childrenPath := filepath.Join(taskPath, task.Name(), "children")
-			content, err := os.ReadFile(childrenPath)
-			if err != nil {
-				continue
-			}
-
-			// 解析children文件内容
-			if len(content) > 0 {
-				// 将内容转换为字符串并分割
-				children := strings.Fields(string(content))
-				for _, child := range children {
-					childrenPIDs = append(childrenPIDs, child)
-					// 递归获取子进程的子进程
-					if err := getChildren(child); err != nil {
-						fmt.Printf("获取进程 %s 的子进程失败: %v\n", child, err)
-					}
-				}
-			}
-		}
-		return nil
-	}
-
-	// 开始递归获取所有子进程
-	err := getChildren(pid)
-	if err != nil {
-		return nil, err
-	}
-
-	return childrenPIDs, nil
+// RunWithInterval 每隔 interval 秒调用一次 fn。fn 出错时从 1 秒开始退避重试，
+// 最长等待 interval 秒，成功后恢复正常间隔
+func RunWithInterval(interval int, fn func() error) {
+	runLoop(time.Duration(interval)*time.Second, nil, fn)
 }
-
-
-func RunWithInterval(interval int,dockerRuntimeDir string,dockerDataDir string,fn func (string,string) error) {
-	 var errCount = 0 
-	 var maxTries = 5
-
-      for {
-		 err := fn(dockerRuntimeDir,dockerDataDir)
-		 if err!=nil {
-			fmt.Println(err)
-			errCount++ 
-
-			if errCount > maxTries {
-				break
-			}
-			continue
-		 }
-		time.Sleep(time.Duration(interval) * time.Second)
-	  }
-}
\ No newline at end of file
@@ -0,0 +1,152 @@
+package dockerinfo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+)
+
+// Podman 在 io.container.manager 注解中写入 libpod
+const (
+	containerManager = "io.container.manager"
+	libpodManager    = "libpod"
+)
+
+// userHomeDir 返回用户的主目录，测试中可替换
+var userHomeDir = func(uid string) (string, error) {
+	u, err := user.LookupId(uid)
+	if err != nil {
+		return "", err
+	}
+	return u.HomeDir, nil
+}
+
+// PodmanInfo 结构体用于读取 Podman 的容器信息，包括 root 和各个用户的
+// rootless 容器
+type PodmanInfo struct {
+	Rootful    ContainerStorage // root 的存储目录
+	UserRunDir string           // 用户运行时目录的父目录，如 /run/user
+}
+
+// NewPodmanInfoWithPath 创建PodmanInfo实例
+func NewPodmanInfoWithPath(rootful ContainerStorage, userRunDir string) *PodmanInfo {
+	if rootful.GraphRoot == "" {
+		rootful.GraphRoot = "/var/lib/containers/storage"
+	}
+	if rootful.RunRoot == "" {
+		rootful.RunRoot = "/run/containers/storage"
+	}
+	if userRunDir == "" {
+		userRunDir = "/run/user"
+	}
+	return &PodmanInfo{
+		Rootful:    rootful,
+		UserRunDir: userRunDir,
+	}
+}
+
+// storages 返回 root 和 rootless 用户的存储目录。UserRunDir 下每个含
+// containers 目录的 <uid> 目录视为一个 rootless 用户，其存储目录为
+// ~/.local/share/containers/storage。每次扫描时重新查找，以发现新用户
+func (p *PodmanInfo) storages() []ContainerStorage {
+	var storages []ContainerStorage
+	if pathExists(p.Rootful.GraphRoot) {
+		storages = append(storages, p.Rootful)
+	}
+
+	entries, err := os.ReadDir(p.UserRunDir)
+	if err != nil {
+		return storages
+	}
+	for _, entry := range entries {
+		runRoot := filepath.Join(p.UserRunDir, entry.Name(), "containers")
+		if !entry.IsDir() || !pathExists(runRoot) {
+			continue
+		}
+		home, err := userHomeDir(entry.Name())
+		if err != nil {
+			fmt.Printf("获取用户 %s 主目录失败: %v\n", entry.Name(), err)
+			continue
+		}
+		graphRoot := filepath.Join(home, ".local/share/containers/storage")
+		if pathExists(graphRoot) {
+			storages = append(storages, ContainerStorage{GraphRoot: graphRoot, RunRoot: runRoot})
+		}
+	}
+	return storages
+}
+
+// Name 实现 Runtime 接口
+func (p *PodmanInfo) Name() string {
+	return RuntimePodman
+}
+
+// Detect 判断是否找到 root 或 rootless 的存储目录
+func (p *PodmanInfo) Detect() bool {
+	return len(p.storages()) > 0
+}
+
+// WatchDirs 返回所有存储运行时目录中的容器目录，新的 rootless 用户在下次
+// 扫描时加入
+func (p *PodmanInfo) WatchDirs() []WatchDir {
+	var dirs []WatchDir
+	for _, storage := range p.storages() {
+		dirs = append(dirs, storage.watchDir())
+	}
+	return dirs
+}
+
+// getContainerInfo 获取存储中一个容器的完整信息
+func (p *PodmanInfo) getContainerInfo(storage ContainerStorage, container storageContainer) (*ContainerInfo, error) {
+	annotations, err := storage.annotations(container.ID)
+	if err != nil {
+		return nil, err
+	}
+	// 共用 root 存储的 CRI-O 容器
+	if annotations[containerManager] != libpodManager && annotations[crioPodNamespace] != "" {
+		return nil, errOtherRuntime
+	}
+
+	initPID, err := storage.initProcessPID(container.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := ""
+	if len(container.Names) > 0 {
+		name = container.Names[0]
+	}
+	return &ContainerInfo{
+		ID:      container.ID,
+		Name:    name,
+		InitPID: initPID,
+	}, nil
+}
+
+// Containers 获取所有存储中运行中容器的信息
+func (p *PodmanInfo) Containers() ([]*ContainerInfo, error) {
+	var containersInfo []*ContainerInfo
+	for _, storage := range p.storages() {
+		containers, err := storage.containers()
+		if err != nil {
+			// 用户还没有创建过容器
+			continue
+		}
+		for _, container := range containers {
+			info, err := p.getContainerInfo(storage, container)
+			if errors.Is(err, ErrNotRunning) || errors.Is(err, errOtherRuntime) {
+				continue
+			}
+			if err != nil {
+				// 记录错误但继续处理其他容器
+				fmt.Printf("获取容器 %s 信息失败: %v\n", container.ID, err)
+				continue
+			}
+			containersInfo = append(containersInfo, info)
+		}
+	}
+
+	return containersInfo, nil
+}
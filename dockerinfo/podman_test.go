@@ -0,0 +1,75 @@
+package dockerinfo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// 测试PodmanInfo.Containers方法，包括 rootful 和 rootless 容器
+func TestPodmanContainers(t *testing.T) {
+	rootful := setupTestStorage(t)
+	createTestStorageContainer(t, rootful, "web1", "web", 1001, map[string]string{
+		containerManager: libpodManager,
+	})
+	createTestStorageContainer(t, rootful, "stopped1", "old", 0, map[string]string{
+		containerManager: libpodManager,
+	})
+	// 共用存储的 CRI-O 容器
+	createTestStorageContainer(t, rootful, "crio1", "k8s_router_router", 1002, map[string]string{
+		crioContainerType: "container",
+		crioPodNamespace:  "openshift-ingress",
+	})
+
+	// uid 1000 的 rootless 存储
+	home := t.TempDir()
+	userRunDir := t.TempDir()
+	rootless := ContainerStorage{
+		GraphRoot: filepath.Join(home, ".local/share/containers/storage"),
+		RunRoot:   filepath.Join(userRunDir, "1000", "containers"),
+	}
+	if err := os.MkdirAll(filepath.Join(rootless.GraphRoot, "overlay-containers"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	createTestStorageContainer(t, rootless, "build1", "builder", 2001, map[string]string{
+		containerManager: libpodManager,
+	})
+	// 没有 containers 目录的用户
+	if err := os.MkdirAll(filepath.Join(userRunDir, "1001"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := userHomeDir
+	userHomeDir = func(uid string) (string, error) {
+		if uid == "1000" {
+			return home, nil
+		}
+		return "", errors.New("unknown user")
+	}
+	defer func() { userHomeDir = saved }()
+
+	p := NewPodmanInfoWithPath(rootful, userRunDir)
+	if !p.Detect() {
+		t.Fatal("期望检测到Podman")
+	}
+
+	infos, err := p.Containers()
+	if err != nil {
+		t.Fatalf("获取所有容器信息失败: %v", err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name+"/"+info.InitPID)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "builder/2001" || names[1] != "web/1001" {
+		t.Errorf("期望容器 builder/2001 和 web/1001, 得到 %v", names)
+	}
+
+	empty := NewPodmanInfoWithPath(ContainerStorage{GraphRoot: filepath.Join(home, "nonexistent")}, filepath.Join(home, "nonexistent"))
+	if empty.Detect() {
+		t.Error("没有存储目录时期望检测失败")
+	}
+}
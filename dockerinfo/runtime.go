@@ -0,0 +1,60 @@
+package dockerinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Runtime 是容器运行时的接口，lightmon 通过它读取运行中的容器
+type Runtime interface {
+	// Name 返回运行时名称，即 -runtime 参数的取值
+	Name() string
+	// Detect 判断本机是否存在该运行时的状态目录
+	Detect() bool
+	// Containers 返回所有运行中容器的信息，名称已可直接输出
+	Containers() ([]*ContainerInfo, error)
+	// WatchDirs 返回容器启动、停止时运行时会修改的目录
+	WatchDirs() []WatchDir
+}
+
+// WatchDir 是需要监控的目录，Depth 为同时监控的子目录层数
+type WatchDir struct {
+	Path  string
+	Depth int
+}
+
+// 运行时名称
+const (
+	RuntimeDocker     = "docker"
+	RuntimeContainerd = "containerd"
+	RuntimeCrio       = "crio"
+	RuntimePodman     = "podman"
+)
+
+// SelectRuntime 返回 runtimes 中名为 name 的运行时，name 为 "auto" 时
+// 按顺序返回第一个检测到的运行时。找不到时返回 nil
+func SelectRuntime(name string, runtimes []Runtime) Runtime {
+	for _, r := range runtimes {
+		if name == "auto" && r.Detect() || r.Name() == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// readAnnotations 读取 OCI config.json 中的注解
+func readAnnotations(configPath string) (map[string]string, error) {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReadFile, err)
+	}
+
+	var spec struct {
+		Annotations map[string]string `json:"annotations"`
+	}
+	if err := json.Unmarshal(content, &spec); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReadFile, err)
+	}
+	return spec.Annotations, nil
+}
@@ -0,0 +1,55 @@
+package dockerinfo
+
+import (
+	"testing"
+)
+
+// fakeRuntime 是测试用的 Runtime
+type fakeRuntime struct {
+	name     string
+	detected bool
+}
+
+func (f *fakeRuntime) Name() string                          { return f.name }
+func (f *fakeRuntime) Detect() bool                          { return f.detected }
+func (f *fakeRuntime) Containers() ([]*ContainerInfo, error) { return nil, nil }
+func (f *fakeRuntime) WatchDirs() []WatchDir                 { return nil }
+
+// 测试SelectRuntime函数
+func TestSelectRuntime(t *testing.T) {
+	runtimes := []Runtime{
+		&fakeRuntime{RuntimeDocker, false},
+		&fakeRuntime{RuntimeContainerd, true},
+		&fakeRuntime{RuntimeCrio, true},
+		&fakeRuntime{RuntimePodman, false},
+	}
+
+	tests := []struct {
+		name    string
+		runtime string
+		want    string
+	}{
+		{"自动检测", "auto", RuntimeContainerd},
+		{"指定运行时", RuntimePodman, RuntimePodman},
+		{"未知运行时", "rkt", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectRuntime(tt.runtime, runtimes)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("期望 nil, 得到 %s", got.Name())
+				}
+				return
+			}
+			if got == nil || got.Name() != tt.want {
+				t.Errorf("期望运行时 %s, 得到 %v", tt.want, got)
+			}
+		})
+	}
+
+	if got := SelectRuntime("auto", runtimes[:1]); got != nil {
+		t.Errorf("没有检测到运行时时期望 nil, 得到 %s", got.Name())
+	}
+}
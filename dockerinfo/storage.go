@@ -0,0 +1,70 @@
+package dockerinfo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrNotRunning 表示存储中的容器没有运行
+	ErrNotRunning = errors.New("container not running")
+	// errOtherRuntime 表示容器由共用存储的其他运行时创建
+	errOtherRuntime = errors.New("container of another runtime")
+)
+
+// ContainerStorage 是 containers/storage 的一组存储目录，CRI-O 和 Podman 共用
+type ContainerStorage struct {
+	GraphRoot string // 持久数据目录，如 /var/lib/containers/storage
+	RunRoot   string // 运行时目录，如 /run/containers/storage
+}
+
+// storageContainer 是 overlay-containers/containers.json 中的一条记录
+type storageContainer struct {
+	ID    string   `json:"id"`
+	Names []string `json:"names"`
+}
+
+// containers 从 containers.json 读取存储中所有容器的ID和名称
+func (s ContainerStorage) containers() ([]storageContainer, error) {
+	content, err := os.ReadFile(filepath.Join(s.GraphRoot, "overlay-containers", "containers.json"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPath, err)
+	}
+
+	var containers []storageContainer
+	if err := json.Unmarshal(content, &containers); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReadFile, err)
+	}
+	return containers, nil
+}
+
+// initProcessPID 从运行时目录的 pidfile 读取容器的初始进程ID，容器未运行时
+// 该文件不存在，返回 ErrNotRunning
+func (s ContainerStorage) initProcessPID(containerID string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(s.RunRoot, "overlay-containers", containerID, "userdata", "pidfile"))
+	if os.IsNotExist(err) {
+		return "", ErrNotRunning
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrReadFile, err)
+	}
+	pid := strings.TrimSpace(string(content))
+	if pid == "" {
+		return "", fmt.Errorf("%w: empty pidfile", ErrReadFile)
+	}
+	return pid, nil
+}
+
+// watchDir 返回运行时目录中的容器目录，容器启动时在其下两层写入 pidfile
+func (s ContainerStorage) watchDir() WatchDir {
+	return WatchDir{Path: filepath.Join(s.RunRoot, "overlay-containers"), Depth: 2}
+}
+
+// annotations 读取容器 OCI config.json 的注解
+func (s ContainerStorage) annotations(containerID string) (map[string]string, error) {
+	return readAnnotations(filepath.Join(s.GraphRoot, "overlay-containers", containerID, "userdata", "config.json"))
+}
@@ -1,9 +1,11 @@
 package dockerinfo
 
 import (
-	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/gotoolkits/lightmon/linux"
+
 	"github.com/fanjindong/go-cache"
 )
 
@@ -11,120 +13,162 @@ import (
 var LocalCachesInst *LocalCaches
 var DefualtDockerCacheExpTime time.Duration = 5*time.Minute
 var DefualtConnProccessCacheExpTime time.Duration = 5*time.Minute
-var docker_mode = "k8s"
+// UnknownContainerExpTime is how long a container ID the runtime did not
+// list is remembered, it is only reloaded for again after that.
+var UnknownContainerExpTime time.Duration = 30*time.Second
+var cgroupV2 = linux.CgroupV2Root() != ""
 
+// NoContainerName is the container name of host processes
+const NoContainerName = "NULL"
 
-func NewLocalCaches() {
-	LocalCachesInst = InitLocalCaches()
+// hostProcess marks the processes outside containers in RefreshProccessCache
+var hostProcess = &ContainerInfo{Name: NoContainerName}
+
+// containerIDForPid reads the container ID of a process from
+// /proc/<pid>/cgroup, a variable so tests can replace it.
+var containerIDForPid = linux.ContainerIDForPid
+
+// processStartTicks reads the start time of a process from /proc/<pid>/stat,
+// a variable so tests can replace it.
+var processStartTicks = func(pid string) (uint64, error) {
+	n,err := strconv.Atoi(pid)
+	if err != nil {
+		return 0,err
+	}
+	stat,err := linux.ProcessStatForPid(n)
+	return stat.StartTicks,err
 }
 
-func SetDockerMode(k8s bool){
-	if k8s {
-		docker_mode = "k8s"
-	} else {
-		docker_mode = "docker"
-	}	
+
+func NewLocalCaches() {
+	LocalCachesInst = InitLocalCaches()
 }
 
 //
 // LoadContainerInfosToCache
-// make mapping for container initPid、ppid、childPids with container_info
+// make mapping for container ID and cgroup ID with the container_info of
+// the containers r runs, and drop the containers that stopped since the
+// last load
 // 
-func LoadContainerInfosToCache(dockerRuntimeDir string,dockerDataDir string) error{
-	dckinst := NewDockerInfoWithPath(dockerRuntimeDir,dockerDataDir)
-	infos,err:= dckinst.GetAllContainersInfo()
+func LoadContainerInfosToCache(r Runtime) error{
+	infos,err:= r.Containers()
 	if err != nil {
 		return err 
 	}
 
+	running := make(map[string]bool, len(infos))
 	for _,info:= range infos {
-		childPids,err:= GetChildrenPIDs(info.ParentPID)
-		if err != nil {
-			fmt.Println("ERROR: ",err)
+		running[info.ID] = true
+		if cgroupID,err := linux.CgroupIDForPid(info.InitPID); err == nil {
+			info.CgroupID = cgroupID
+			LocalCachesInst.RefreshCgroupCache.Set(strconv.FormatUint(cgroupID,10),info,cache.WithEx(DefualtDockerCacheExpTime))
 		}
 
-		if len(info.Name)>1 {
-			info.Name = info.Name[1:]
-		} 
-	
-		if !LocalCachesInst.RefreshContainerCache.Exists(info.InitPID) {
-			LocalCachesInst.RefreshContainerCache.Set(info.InitPID,info,cache.WithEx(DefualtDockerCacheExpTime))
-		}
+		LocalCachesInst.RefreshContainerCache.Set(info.ID,info,cache.WithEx(DefualtDockerCacheExpTime))
+	}
 
-		if !LocalCachesInst.RefreshContainerCache.Exists(info.ParentPID) {
-			LocalCachesInst.RefreshContainerCache.Set(info.ParentPID,info,cache.WithEx(DefualtDockerCacheExpTime))
+	for id,v := range LocalCachesInst.RefreshContainerCache.ToMap() {
+		if running[id] {
+			continue
 		}
-
-		for _,pid := range childPids {
-			if !LocalCachesInst.RefreshContainerCache.Exists(info.InitPID)  {
-				LocalCachesInst.RefreshContainerCache.Set(pid,info,cache.WithEx(DefualtDockerCacheExpTime))
-			}
+		LocalCachesInst.RefreshContainerCache.Del(id)
+		key := strconv.FormatUint(v.(*ContainerInfo).CgroupID,10)
+		if cur,ok := LocalCachesInst.RefreshCgroupCache.Get(key); ok && cur == v {
+			LocalCachesInst.RefreshCgroupCache.Del(key)
 		}
 	}
 	return nil
 }
 
 
+//
+// GetContainerName
+// get container name by the cgroup ID recorded in the kernel, see
+// LookupContainer. NoContainerName for host processes.
+//
+func GetContainerName(cgroupID uint64, pid string, startTicks uint64) string {
+	info := LookupContainer(cgroupID, pid, startTicks)
+	if info == nil {
+		return NoContainerName
+	}
+	return info.Name
+}
+
+//
+// LookupContainer
+// get the container by the cgroup ID recorded in the kernel, falls back to
+// the container ID in /proc/<pid>/cgroup when the cgroup ID is unknown.
+// startTicks is the process start time from /proc/<pid>/stat, so a reused
+// pid neither hits the cache entry of the previous process nor is looked up
+// in /proc. 0 if unknown. nil for host processes.
+//
+func LookupContainer(cgroupID uint64, pid string, startTicks uint64) *ContainerInfo {
+	if cgroupID != 0 && cgroupV2 {
+		info,ok := LocalCachesInst.RefreshCgroupCache.Get(strconv.FormatUint(cgroupID,10))
+		if ok {
+			return info.(*ContainerInfo)
+		}
+	}
+
+	key := pid
+	if startTicks != 0 {
+		key = pid + "@" + strconv.FormatUint(startTicks,10)
+	}
+	return lookupContainerByProcess(pid, key, startTicks)
+}
+
 //
 // GetContainerNameFromConnProcessCacheByPid
 // get container name by pid using match container_cache
 //
 func GetContainerNameFromConnProcessCacheByPid(pid string) string {
-	if !LocalCachesInst.RefreshProccessCache.Exists(pid) {
+	info := lookupContainerByProcess(pid, pid, 0)
+	if info == nil {
+		return NoContainerName
+	}
+	return info.Name
+}
 
-		// to match Container_cache by pid
-		info,ok:=LocalCachesInst.RefreshContainerCache.Get(pid)
-		if ok {
-			containerName:= info.(*ContainerInfo).Name
-			LocalCachesInst.RefreshProccessCache.Set(pid,containerName,cache.WithEx(DefualtConnProccessCacheExpTime))
-			return containerName
+// lookupContainerByProcess caches the container of pid under key. With
+// startTicks, /proc/<pid> is only used if the process started then.
+func lookupContainerByProcess(pid string, key string, startTicks uint64) *ContainerInfo {
+	if info,ok := LocalCachesInst.RefreshProccessCache.Get(key); ok {
+		if info == hostProcess {
+			return nil
 		}
+		return info.(*ContainerInfo)
+	}
 
-		// to match Container_cache by root ppid
-
-		var ppid = "" 
-		var err error
-
-		if docker_mode == "k8s" {
-			ppid,err =GetRootPrevParentPID(pid)
-			if err != nil {
-				fmt.Println("ERROR: ",err)
-			}
-		} else {
-			ppid,err=GetRootParentPID(pid)
-			if err != nil {
-				fmt.Println("ERROR: ",err)
-			}
+	if startTicks != 0 {
+		if ticks,err := processStartTicks(pid); err != nil || ticks != startTicks {
+			// Gone, or the PID was reused by another process.
+			return nil
 		}
+	}
 
-		if LocalCachesInst.RefreshContainerCache.Exists(ppid) {
-			// match containers cache
-			info,_:=LocalCachesInst.RefreshContainerCache.Get(ppid)
-			containerName:= info.(*ContainerInfo).Name
-			// update proccess cache
-			LocalCachesInst.RefreshProccessCache.Set(pid,containerName,cache.WithEx(DefualtConnProccessCacheExpTime))
-			return containerName
-		}
+	containerID,err := containerIDForPid(pid)
+	if err != nil {
+		// The process is gone, nothing to cache.
+		return nil
+	}
+	if containerID == "" {
+		LocalCachesInst.RefreshProccessCache.Set(key,hostProcess,cache.WithEx(DefualtConnProccessCacheExpTime))
+		return nil
+	}
 
-		// 
-		// err = LoadContainerInfosToCache()
-		// if err !=nil {
-		// 	fmt.Println("ERROR: ",err)
-		// 	return "NULL"
-		// }
-		// info,ok:=LocalCachesInst.RefreshContainerCache.Get(pid)
-		// if ok {
-		// 	containerName:= info.(ContainerInfo).Name
-		// 	LocalCachesInst.RefreshProccessCache.Set(pid,containerName,cache.WithEx(DefualtConnProccessCacheExpTime))
-		// 	return containerName
-		// }
-		LocalCachesInst.RefreshProccessCache.Set(pid,"NULL")
-		return "NULL"
-		
+	info,ok := LocalCachesInst.RefreshContainerCache.Get(containerID)
+	if !ok {
+		// Not loaded yet, look again on the next event. Sandboxes and the
+		// containers of other runtimes are never listed, so reload once per
+		// UnknownContainerExpTime for each of them.
+		if _,ok := LocalCachesInst.UnknownContainerCache.Get(containerID); !ok {
+			LocalCachesInst.UnknownContainerCache.Set(containerID,true,cache.WithEx(UnknownContainerExpTime))
+			requestReload()
+		}
+		return nil
 	}
-	
-	containerName,_:=LocalCachesInst.RefreshProccessCache.Get(pid)
-	return containerName.(string)
+	LocalCachesInst.RefreshProccessCache.Set(key,info,cache.WithEx(DefualtConnProccessCacheExpTime))
+	return info.(*ContainerInfo)
 }
 
 
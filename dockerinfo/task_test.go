@@ -0,0 +1,155 @@
+package dockerinfo
+
+import (
+	"os"
+	"testing"
+
+	"github.com/fanjindong/go-cache"
+)
+
+func TestGetContainerName(t *testing.T) {
+	NewLocalCaches()
+	saved := cgroupV2
+	cgroupV2 = true
+	defer func() { cgroupV2 = saved }()
+
+	info := &ContainerInfo{ID: "0123abcd", Name: "nginx", CgroupID: 4242}
+	LocalCachesInst.RefreshCgroupCache.Set("4242", info, cache.WithEx(DefualtDockerCacheExpTime))
+
+	tests := []struct {
+		name     string
+		cgroupID uint64
+		want     string
+	}{
+		{"container cgroup", 4242, "nginx"},
+		{"host cgroup", 1, "NULL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No such PID, the process ancestry must not be used.
+			if got := GetContainerName(tt.cgroupID, "32769", 0); got != tt.want {
+				t.Errorf("GetContainerName(%d) = %s; want %s", tt.cgroupID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetContainerNameByProcess(t *testing.T) {
+	NewLocalCaches()
+	saved := containerIDForPid
+	defer func() { containerIDForPid = saved }()
+
+	const id = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	ids := map[string]string{"100": id, "200": "", "300": "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"}
+	containerIDForPid = func(pid string) (string, error) {
+		id, ok := ids[pid]
+		if !ok {
+			return "", os.ErrNotExist
+		}
+		return id, nil
+	}
+
+	info := &ContainerInfo{ID: id, Name: "nginx"}
+	LocalCachesInst.RefreshContainerCache.Set(id, info, cache.WithEx(DefualtDockerCacheExpTime))
+
+	tests := []struct {
+		name string
+		pid  string
+		want string
+	}{
+		{"container", "100", "nginx"},
+		{"host", "200", "NULL"},
+		{"unknown container", "300", "NULL"},
+		{"gone", "400", "NULL"},
+	}
+
+	// Drop earlier reload requests.
+	select {
+	case <-reloadRequests:
+	default:
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// cgroup ID 0, e.g. from the kprobe program
+			if got := GetContainerName(0, tt.pid, 0); got != tt.want {
+				t.Errorf("GetContainerName(%s) = %s; want %s", tt.pid, got, tt.want)
+			}
+		})
+	}
+
+	select {
+	case <-reloadRequests:
+	default:
+		t.Error("unknown container should request a reload")
+	}
+
+	// Containers the runtime never lists are not reloaded for again.
+	GetContainerName(0, "300", 0)
+	select {
+	case <-reloadRequests:
+		t.Error("unknown container requested a reload again")
+	default:
+	}
+
+	// Containers not loaded yet are looked up again.
+	LocalCachesInst.RefreshContainerCache.Set(ids["300"], &ContainerInfo{ID: ids["300"], Name: "redis"}, cache.WithEx(DefualtDockerCacheExpTime))
+	if got := GetContainerName(0, "300", 0); got != "redis" {
+		t.Errorf("GetContainerName(300) = %s after loading; want redis", got)
+	}
+}
+
+// staticRuntime 是返回固定容器的 Runtime
+type staticRuntime struct {
+	fakeRuntime
+	infos []*ContainerInfo
+}
+
+func (s *staticRuntime) Containers() ([]*ContainerInfo, error) { return s.infos, nil }
+
+func TestLoadContainerInfosToCache(t *testing.T) {
+	NewLocalCaches()
+	stopped := &ContainerInfo{ID: "stopped", Name: "redis", CgroupID: 4242}
+	LocalCachesInst.RefreshContainerCache.Set(stopped.ID, stopped, cache.WithEx(DefualtDockerCacheExpTime))
+	LocalCachesInst.RefreshCgroupCache.Set("4242", stopped, cache.WithEx(DefualtDockerCacheExpTime))
+
+	// No such PID, so no cgroup ID either.
+	r := &staticRuntime{infos: []*ContainerInfo{{ID: "running", Name: "nginx", InitPID: "-1"}}}
+	if err := LoadContainerInfosToCache(r); err != nil {
+		t.Fatalf("LoadContainerInfosToCache() error = %v", err)
+	}
+
+	if _, ok := LocalCachesInst.RefreshContainerCache.Get("running"); !ok {
+		t.Error("running container not loaded")
+	}
+	if _, ok := LocalCachesInst.RefreshContainerCache.Get("stopped"); ok {
+		t.Error("stopped container still cached")
+	}
+	if _, ok := LocalCachesInst.RefreshCgroupCache.Get("4242"); ok {
+		t.Error("cgroup of the stopped container still cached")
+	}
+}
+
+func TestLookupContainerReusedPid(t *testing.T) {
+	NewLocalCaches()
+	savedID, savedTicks := containerIDForPid, processStartTicks
+	defer func() { containerIDForPid, processStartTicks = savedID, savedTicks }()
+
+	const id = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	containerIDForPid = func(pid string) (string, error) { return id, nil }
+	// The process now running as 100 started at tick 200.
+	processStartTicks = func(pid string) (uint64, error) { return 200, nil }
+	info := &ContainerInfo{ID: id, Name: "nginx"}
+	LocalCachesInst.RefreshContainerCache.Set(id, info, cache.WithEx(DefualtDockerCacheExpTime))
+
+	if got := LookupContainer(0, "100", 100); got != nil {
+		t.Errorf("LookupContainer() of a reused pid = %s; want nil", got.Name)
+	}
+	if _, ok := LocalCachesInst.RefreshProccessCache.Get("100@100"); ok {
+		t.Error("reused pid should not be cached")
+	}
+	if got := LookupContainer(0, "100", 200); got != info {
+		t.Errorf("LookupContainer() of the current process = %v; want nginx", got)
+	}
+}
@@ -0,0 +1,90 @@
+package dockerinfo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gotoolkits/lightmon/linux"
+)
+
+// minReloadInterval 是两次由变化触发的加载之间的最短间隔，避免频繁变化时
+// 连续扫描
+var minReloadInterval = time.Second
+
+// retryBackoff 是出错后第一次重试的等待时间
+var retryBackoff = time.Second
+
+// reloadRequests 通知 WatchContainers 立即重新加载容器信息
+var reloadRequests = make(chan struct{}, 1)
+
+// requestReload 请求重新加载容器信息，已有未处理的请求时忽略
+func requestReload() {
+	select {
+	case reloadRequests <- struct{}{}:
+	default:
+	}
+}
+
+// WatchContainers 加载 r 的容器信息到缓存，此后在 r 的状态目录发生变化、
+// 或事件来自未加载的容器时立即重新加载。另外每隔 interval 完整扫描一次，
+// 补上遗漏的变化。无法使用 inotify 时退回到每隔 pollInterval 扫描
+func WatchContainers(r Runtime, interval time.Duration, pollInterval time.Duration) {
+	load := func() error { return LoadContainerInfosToCache(r) }
+
+	watcher, err := linux.NewDirWatcher()
+	if err != nil {
+		fmt.Printf("监控容器运行时目录失败，改为每 %v 扫描: %v\n", pollInterval, err)
+		runLoop(pollInterval, reloadRequests, load)
+		return
+	}
+	go func() {
+		for watcher.Wait() == nil {
+			requestReload()
+		}
+	}()
+
+	runLoop(interval, reloadRequests, func() error {
+		// 目录可能在启动后才创建，如第一个容器启动时，每次加载前重新添加
+		for _, dir := range r.WatchDirs() {
+			watcher.Add(dir.Path, dir.Depth)
+		}
+		return load()
+	})
+}
+
+// runLoop 调用 fn，然后等待 interval 或 changes 中的通知后再次调用。fn 出错时
+// 忽略通知，从 retryBackoff 开始成倍退避重试，最长等待 interval，成功后恢复
+func runLoop(interval time.Duration, changes <-chan struct{}, fn func() error) {
+	var backoff time.Duration
+	for {
+		start := time.Now()
+		wait, wake := interval, changes
+		if err := fn(); err != nil {
+			backoff = nextBackoff(backoff, interval)
+			wait, wake = backoff, nil
+			fmt.Printf("%v, %v 后重试\n", err, wait)
+		} else {
+			backoff = 0
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-wake:
+			timer.Stop()
+			time.Sleep(time.Until(start.Add(minReloadInterval)))
+		}
+	}
+}
+
+// nextBackoff 返回下一次重试的等待时间，从 retryBackoff 开始翻倍，最长 max
+func nextBackoff(cur time.Duration, max time.Duration) time.Duration {
+	next := 2 * cur
+	if next == 0 {
+		next = retryBackoff
+	}
+	if next > max {
+		next = max
+	}
+	return next
+}
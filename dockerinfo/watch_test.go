@@ -0,0 +1,61 @@
+package dockerinfo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNextBackoff(t *testing.T) {
+	tests := []struct {
+		cur  time.Duration
+		want time.Duration
+	}{
+		{0, time.Second},
+		{time.Second, 2 * time.Second},
+		{4 * time.Second, 8 * time.Second},
+		{8 * time.Second, 10 * time.Second},
+		{10 * time.Second, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := nextBackoff(tt.cur, 10*time.Second); got != tt.want {
+			t.Errorf("nextBackoff(%v) = %v; want %v", tt.cur, got, tt.want)
+		}
+	}
+}
+
+func TestRunLoop(t *testing.T) {
+	savedBackoff, savedMin := retryBackoff, minReloadInterval
+	retryBackoff, minReloadInterval = time.Millisecond, 0
+	defer func() { retryBackoff, minReloadInterval = savedBackoff, savedMin }()
+
+	// Keeps retrying well past the five errors it used to give up after,
+	// then recovers and reloads on changes.
+	calls := make(chan int)
+	changes := make(chan struct{}, 1)
+	n := 0
+	go runLoop(time.Hour, changes, func() error {
+		n++
+		calls <- n
+		if n <= 10 {
+			return errors.New("runtime not ready")
+		}
+		return nil
+	})
+
+	timeout := time.After(5 * time.Second)
+	for want := 1; want <= 12; want++ {
+		if want == 12 {
+			changes <- struct{}{}
+		}
+		select {
+		case got := <-calls:
+			if got != want {
+				t.Fatalf("call %d; want %d", got, want)
+			}
+		case <-timeout:
+			t.Fatalf("runLoop stopped after %d calls", want-1)
+		}
+	}
+}
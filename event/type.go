@@ -11,10 +11,129 @@ type TcpEvent struct {
     Uid    uint32    
     Sport  uint16     
     Dport  uint16 
+    Af     uint16 // Address Family
+    Pad    uint16
     Saddr  uint32
     Daddr  uint32     
 	TsUs   uint64
+	Saddr6 [2]uint64 // AF_INET6 source address
+	Daddr6 [2]uint64 // AF_INET6 destination address
+	TaskIDs
+	Ret    int32     // connect() return code
 }
+// Directions of TcpStateEvent, DIRECTION_* in tcpStateSrc.c
+const (
+	StateDirectionUnknown  = 0
+	StateDirectionOutbound = 1
+	StateDirectionInbound  = 2
+)
+
+// TcpStateEvent represents a TCP state transition from sock:inet_sock_set_state
+type TcpStateEvent struct {
+	Comm     [16]uint8
+	Pid      uint32
+	Uid      uint32
+	Sport    uint16
+	Dport    uint16
+	Af       uint16 // Address Family
+	Direction uint16 // StateDirection*
+	Saddr    uint32
+	Daddr    uint32
+	TsUs     uint64
+	Saddr6   [2]uint64 // AF_INET6 source address
+	Daddr6   [2]uint64 // AF_INET6 destination address
+	TaskIDs
+	OldState int32
+	NewState int32
+}
+
+// FlowEvent represents the counters of a TCP connection, sent when it closes
+type FlowEvent struct {
+	Comm      [16]uint8
+	Pid       uint32
+	Uid       uint32
+	Sport     uint16
+	Dport     uint16
+	Af        uint16 // Address Family
+	Inbound   uint16 // 1 if the socket was created by a listener
+	Saddr     uint32
+	Daddr     uint32
+	TsUs      uint64    // close time
+	Saddr6    [2]uint64 // AF_INET6 source address
+	Daddr6    [2]uint64 // AF_INET6 destination address
+	TaskIDs
+	StartUs   uint64    // time the connection was established
+	TxBytes   uint64
+	RxBytes   uint64
+	TxPackets uint32
+	RxPackets uint32
+}
+
+// TcpRetransEvent represents a TCP retransmission or reset from the tcp tracepoints
+type TcpRetransEvent struct {
+	Comm       [16]uint8
+	Pid        uint32
+	Uid        uint32
+	Sport      uint16
+	Dport      uint16
+	Af         uint16 // Address Family
+	Pad        uint16
+	Saddr      uint32
+	Daddr      uint32
+	TsUs       uint64
+	Saddr6     [2]uint64 // AF_INET6 source address
+	Daddr6     [2]uint64 // AF_INET6 destination address
+	TaskIDs
+	Kind       uint32    // 1 retransmit, 2 reset sent, 3 reset received
+	State      int32     // TCP state, 0 if unknown
+	Suppressed uint32    // events dropped by the rate limit since the last one
+	Pad2       uint32
+}
+
+// DnsEvent represents a DNS query sent by udp_sendmsg/udpv6_sendmsg or the
+// response read back from the same socket
+type DnsEvent struct {
+	Comm     [16]uint8
+	Pid      uint32
+	Uid      uint32
+	Sport    uint16
+	Dport    uint16
+	Af       uint16 // Address Family
+	Pad      uint16
+	Saddr    uint32
+	Daddr    uint32
+	TsUs     uint64
+	Saddr6   [2]uint64 // AF_INET6 source address
+	Daddr6   [2]uint64 // AF_INET6 destination address
+	TaskIDs
+	Len      uint32    // number of valid bytes in Payload
+	Response uint32    // 1 for responses received on a socket that sent a query
+	Payload  [512]uint8 // raw DNS message, possibly truncated
+}
+
+// TaskIDs identifies the cgroup and namespaces of the process that caused
+// an event, see headers/task_ids.h
+type TaskIDs struct {
+	CgroupId uint64 // cgroup v2 ID
+	StartNs  uint64 // process start time in ns since boot, 0 if unknown
+	NetNs    uint32 // network namespace inode, 0 if unknown
+	PidNs    uint32 // PID namespace inode, 0 if unknown
+	Tid      uint32 // thread ID
+	PPid     uint32 // TGID of the parent process, 0 if unknown
+}
+
+// ProcEvent is the fixed part of an exec or exit event from
+// sched_process_exec/sched_process_exit. Exec events are followed by the
+// NUL terminated file name in 256 bytes and ArgsLen bytes of NUL separated
+// arguments.
+type ProcEvent struct {
+	Kind      uint32 // 1 exec, 2 exit
+	Pid       uint32
+	TsUs      uint64
+	ArgsLen   uint32
+	ArgsCount uint32
+}
+
 // Event is a common event interface
 type Event struct {
 	TsUs uint64
@@ -22,6 +141,8 @@ type Event struct {
 	UID  uint32
 	Af   uint16 // Address Family
 	Task [16]byte
+	Ret  int32 // connect() return code
+	TaskIDs
 }
 
 // IP4Event represents a socket connect event from AF_INET(4)
@@ -44,20 +165,124 @@ type OtherSocketEvent struct {
 	Event
 }
 
+// UnixEvent represents a socket connect event from AF_UNIX
+type UnixEvent struct {
+	Event
+	PathLen uint32     // length of the address passed to connect(), without sun_family
+	Path    [108]uint8 // sun_path, abstract names start with a NUL byte
+}
+
+// SummaryKey is the key of the conn_summary map, see headers/summary.h
+type SummaryKey struct {
+	CgroupId uint64
+	Pid      uint32
+	Dport    uint16
+	Proto    uint8
+	Af       uint8
+	Daddr    [16]uint8 // AF_INET addresses in the first 4 bytes
+}
+
+// SummaryValue counts the connects of a SummaryKey since the map was last
+// drained
+type SummaryValue struct {
+	Count   uint64
+	Failed  uint64 // connects that failed, EINPROGRESS is not a failure
+	FirstNs uint64 // bpf_ktime_get_ns() of the first connect
+	LastNs  uint64 // bpf_ktime_get_ns() of the last connect
+	Comm    [16]uint8
+	Uid     uint32
+	Pad     uint32
+	TaskIDs        // of the last connect
+}
+
+// EventPayload types
+const (
+	TypeConnect = "connect"
+	TypeState   = "state"
+	TypeAccept  = "accept"
+	TypeSend    = "send"
+	TypeDNS     = "dns"
+	TypeFlow    = "flow"
+	TypeSummary = "summary"
+
+	TypeRetransmit    = "retransmit"
+	TypeResetSent     = "rst_sent"
+	TypeResetReceived = "rst_received"
+)
+
+// EventPayload protocols
+const (
+	ProtocolTCP  = "tcp"
+	ProtocolUDP  = "udp"
+	ProtocolUnix = "unix"
+)
+
+// EventPayload directions
+const (
+	DirectionOutbound = "outbound"
+	DirectionInbound  = "inbound"
+	// DirectionUnknown is used for state events of sockets whose owner is unknown
+	DirectionUnknown  = "unknown"
+)
+
 type EventPayload struct {
 	// KernelTime    string  `json:"kernelTime"`
 	UTime        time.Time `json:"uTime"`
+	Type          string `json:"type"`
+	Direction     string `json:"direction"`
+	Protocol      string `json:"protocol"`
 	AddressFamily string `json:"addressFamily"`
+	EbpfType      string `json:"ebpfType"`
 	Pid           uint32 `json:"pid"`
+	Tid           uint32 `json:"tid"`
+	PPid          uint32 `json:"ppid"`
 	ProcessPath   string `json:"processPath"`
 	ProcessArgs   string `json:"processArgs"`
+	ParentPath    string `json:"parentPath"`
+	ProcessStartTime time.Time `json:"processStartTime"`
 	User          string `json:"user"`
 	Comm          string `json:"comm"`
 	Host          string `json:"host"`
 	DestIP        net.IP `json:"dip"`
 	DestPort      uint16 `json:"dport"`
+	SocketPath    string `json:"socketPath"`
 	SrcIP         net.IP `json:"sip"`
 	SrcPort       uint16 `json:"sport"`
 	State	      string `json:"state"`
+	OldState      string `json:"oldState"`
+	QName         string `json:"qname"`
+	QType         string `json:"qtype"`
+	Result        string `json:"result"`
+	Errno         int32  `json:"errno"`
+	Suppressed    uint32 `json:"suppressed"`
 	ConatinerName string `json:"conatinerName"`
+	PodName       string `json:"podName"`
+	PodNamespace  string `json:"podNamespace"`
+	// ContainerID scopes the DNS host cache, container names repeat across pods
+	ContainerID   string `json:"-"`
+	CgroupID      uint64 `json:"cgroupId"`
+	NetNs         uint32 `json:"netns"`
+	PidNs         uint32 `json:"pidns"`
+	Flow          *Flow  `json:"flow,omitempty"`
+	Summary       *Summary `json:"summary,omitempty"`
+}
+
+// Flow holds the byte and packet counters of a closed TCP connection
+type Flow struct {
+	StartTime  time.Time `json:"startTime"`
+	EndTime    time.Time `json:"endTime"`
+	DurationMs int64     `json:"durationMs"`
+	TxBytes    uint64    `json:"txBytes"`
+	RxBytes    uint64    `json:"rxBytes"`
+	TxPackets  uint32    `json:"txPackets"`
+	RxPackets  uint32    `json:"rxPackets"`
+}
+
+// Summary holds the connects aggregated in the kernel for one process and
+// destination during a -summary interval
+type Summary struct {
+	Count     uint64    `json:"count"`
+	Failed    uint64    `json:"failed"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
 }
\ No newline at end of file
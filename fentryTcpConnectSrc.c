@@ -1,100 +1,147 @@
 //go:build ignore
 
 #include "common.h"
+#include "vmlinux_core.h"
 
 #include "bpf_endian.h"
 #include "bpf_tracing.h"
+#include "bpf_core_read.h"
+#include "exclude.h"
+#define TASK_IDS_CORE
+#include "task_ids.h"
+#include "scope.h"
+#include "summary.h"
 
 #define AF_INET 2
+#define AF_INET6 10
+#define IPPROTO_TCP 6
 #define TASK_COMM_LEN 16
 
-char __license[] SEC("license") = "Dual MIT/GPL";
+// bpf_ringbuf_submit flags and bpf_ringbuf_query selectors.
+#define BPF_RB_NO_WAKEUP 1
+#define BPF_RB_AVAIL_DATA 0
 
-/**
- * This code copies parts of struct sock_common and struct sock from
- * the Linux kernel, but doesn't cause any CO-RE information to be emitted
- * into the ELF object. This requires the struct layout (up until the fields
- * that are being accessed) to match the kernel's, and the example will break
- * or misbehave when this is no longer the case.
- *
- * Also note that BTF-enabled programs like fentry, fexit, fmod_ret, tp_btf,
- * lsm, etc. declared using the BPF_PROG macro can read kernel memory without
- * needing to call bpf_probe_read*().
- */
+char __license[] SEC("license") = "Dual MIT/GPL";
 
 /**
- * struct sock_common reflects the start of the kernel's struct sock_common.
- * It only contains the fields up until skc_family that are accessed in the
- * program, with padding to match the kernel's declaration.
+ * struct sock is declared in vmlinux_core.h with preserve_access_index, so
+ * every field read below is relocated against the kernel's BTF at load time
+ * and the program runs unchanged across kernel versions.
  */
-struct sock_common {
-	union {
-		struct {
-			__be32 skc_daddr;
-			__be32 skc_rcv_saddr;
-		};
-	};
-	union {
-		// Padding out union skc_hash.
-		__u32 _;
-	};
-	union {
-		struct {
-			__be16 skc_dport;
-			__u16 skc_num;
-		};
-	};
-	short unsigned int skc_family;
-};
-
-/**
- * struct sock reflects the start of the kernel's struct sock.
- */
-struct sock {
-	struct sock_common __sk_common;
-};
 
+// The size of events is set from ringBufferSize when loading.
 struct {
 	__uint(type, BPF_MAP_TYPE_RINGBUF);
 	__uint(max_entries, 1 << 24);
 	__type(value, struct event);
 } events SEC(".maps");
 
+// ringbuf_drops counts, per CPU, the events that did not fit in events.
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, u32);
+	__type(value, u64);
+} ringbuf_drops SEC(".maps");
+
+// Userspace is only woken up once this many bytes are waiting in events,
+// 0 wakes it up for every event. Set from bufferWatermark when loading.
+const volatile __u64 ringbuf_wakeup_bytes = 0;
+
 struct event {
 	u8 comm[16];
     __u32 pid;
     __u32 uid;
 	__u16 sport;
-	__be16 dport;
+	__u16 dport;
+	__u16 af;
+	__u16 pad;
 	__be32 saddr;
 	__be32 daddr;
 	__u64 ts_us;
+	__u8 saddr6[16];
+	__u8 daddr6[16];
+	struct task_ids ids;
+	__s32 ret;
+	__u32 pad2;
 };
 
-SEC("fentry/tcp_connect")
-int BPF_PROG(tcp_connect, struct sock *sk) {
-	if (!sk || sk->__sk_common.skc_family != AF_INET) {
-        return 0;
-    }
+/**
+ * The event is built when inet_stream_connect returns, so connects that fail
+ * before a SYN is sent (ENETUNREACH, EHOSTUNREACH, EADDRNOTAVAIL, ...) are
+ * reported too. The destination is taken from the address passed to
+ * connect(), as the socket only records it once a route was found. The
+ * source address and port are 0 when the connect failed that early.
+ */
+SEC("fexit/inet_stream_connect")
+int BPF_PROG(inet_stream_connect_exit, struct socket *sock, struct sockaddr *uaddr, int addr_len, int flags, int ret) {
+	if (!sock || !uaddr || !in_scope()) {
+		return 0;
+	}
+	struct sock *sk = BPF_CORE_READ(sock, sk);
+	if (!sk) {
+		return 0;
+	}
+
+	// AF_UNSPEC disconnects the socket.
+	u16 family = 0;
+	bpf_probe_read_kernel(&family, sizeof(family), &uaddr->sa_family);
+	if (family != AF_INET && family != AF_INET6) {
+		return 0;
+	}
+
+	struct event tcp_info = {};
+	if (family == AF_INET) {
+		struct sockaddr_in sin = {};
+		bpf_probe_read_kernel(&sin, sizeof(sin), uaddr);
+		tcp_info.dport = bpf_ntohs(sin.sin_port);
+		tcp_info.daddr = sin.sin_addr.s_addr;
+		tcp_info.saddr = BPF_CORE_READ(sk, __sk_common.skc_rcv_saddr);
+	} else if (bpf_core_field_exists(sk->__sk_common.skc_v6_daddr)) {
+		struct sockaddr_in6 sin6 = {};
+		bpf_probe_read_kernel(&sin6, sizeof(sin6), uaddr);
+		tcp_info.dport = bpf_ntohs(sin6.sin6_port);
+		__builtin_memcpy(tcp_info.daddr6, sin6.sin6_addr.in6_u.u6_addr8, sizeof(tcp_info.daddr6));
+		BPF_CORE_READ_INTO(&tcp_info.saddr6, sk, __sk_common.skc_v6_rcv_saddr.in6_u.u6_addr8);
+	} else {
+		return 0;
+	}
+	if (is_excluded(family, tcp_info.daddr, tcp_info.daddr6, tcp_info.dport)) {
+		return 0;
+	}
 
-	u32 pid = (u32)(bpf_get_current_pid_tgid() >> 32);
-	u32 uid = (u32)bpf_get_current_uid_gid();
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	tcp_info.ts_us = bpf_ktime_get_ns() / 1000;
+	tcp_info.pid = (u32)(pid_tgid >> 32);
+	tcp_info.uid = (u32)bpf_get_current_uid_gid();
+	tcp_info.af = family;
+	tcp_info.sport = BPF_CORE_READ(sk, __sk_common.skc_num);
+	tcp_info.ret = ret;
+	bpf_get_current_comm(&tcp_info.comm, TASK_COMM_LEN);
+	tcp_info.ids = current_task_ids();
 
-	struct event *tcp_info;
-	tcp_info = bpf_ringbuf_reserve(&events, sizeof(struct event), 0);
-	if (!tcp_info) {
+	if (summary_mode()) {
+		summary_add(tcp_info.af, tcp_info.daddr, tcp_info.af == AF_INET6 ? tcp_info.daddr6 : 0, tcp_info.dport,
+		            IPPROTO_TCP, tcp_info.pid, tcp_info.uid, tcp_info.comm, &tcp_info.ids, ret);
 		return 0;
 	}
 
-	tcp_info->ts_us = bpf_ktime_get_ns() / 1000;
-    tcp_info->pid = pid;
-    tcp_info->uid = uid;
-	tcp_info->saddr = sk->__sk_common.skc_rcv_saddr;
-	tcp_info->daddr = sk->__sk_common.skc_daddr;
-	tcp_info->dport = sk->__sk_common.skc_dport;
-	tcp_info->sport = bpf_htons(sk->__sk_common.skc_num);
-	bpf_get_current_comm(&tcp_info->comm, TASK_COMM_LEN);
+	struct event *event;
+	event = bpf_ringbuf_reserve(&events, sizeof(struct event), 0);
+	if (!event) {
+		u32 zero = 0;
+		u64 *drops = bpf_map_lookup_elem(&ringbuf_drops, &zero);
+		if (drops) {
+			*drops += 1;
+		}
+		return 0;
+	}
+	__builtin_memcpy(event, &tcp_info, sizeof(struct event));
 
-	bpf_ringbuf_submit(tcp_info, 0);
+	u64 wakeup = 0;
+	if (ringbuf_wakeup_bytes && bpf_ringbuf_query(&events, BPF_RB_AVAIL_DATA) < ringbuf_wakeup_bytes) {
+		wakeup = BPF_RB_NO_WAKEUP;
+	}
+	bpf_ringbuf_submit(event, wakeup);
 	return 0;
-}
\ No newline at end of file
+}
@@ -0,0 +1,78 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"log"
+
+	. "github.com/gotoolkits/lightmon/event"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/features"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/ringbuf"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 fentry fentryTcpConnectSrc.c -- -Iheaders/
+
+func setupBpfFentryWorkers() {
+	err := features.HaveProgramType(ebpf.Tracing)
+	if errors.Is(err, ebpf.ErrNotSupported) {
+		log.Fatalln("tracing program type is not supported")
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	spec, err := loadFentry()
+	if err != nil {
+		log.Fatalf("loading fentry spec: %v", err)
+	}
+	setKernelScope(spec)
+	setRingbufSpec(spec, "events")
+	setSummarySpec(spec)
+
+	// Load pre-compiled programs and maps into the kernel.
+	objs := fentryObjects{}
+	if err := spec.LoadAndAssign(&objs, collectionOptions()); err != nil {
+		log.Fatalf("loading fentry objects: %v", err)
+	}
+	addCloser(&objs)
+	loadKernelExclusions(objs.ExcludedPorts, objs.ExcludedNets)
+
+	lnkExit, err := link.AttachTracing(link.TracingOptions{
+		Program:    objs.InetStreamConnectExit,
+		AttachType: ebpf.AttachTraceFExit,
+	})
+	if err != nil {
+		log.Fatalf("attaching fexit: %v", err)
+	}
+	addCloser(lnkExit)
+
+	ringb := newRingbufReader("fentry", objs.Events, objs.RingbufDrops)
+	startSummary("fentry", objs.ConnSummary, objs.SummaryDrops)
+
+	go (func() {
+		for {
+			if !readTcpEvents(ringb) {
+				return
+			}
+		}
+	})()
+}
+
+func readTcpEvents(rb *ringbuf.Reader) bool {
+	var event TcpEvent
+	sample, ok := readRingbufSample(rb, "fentry")
+	if !ok {
+		return false
+	}
+	if sample == nil || !decodeSample("fentry", sample, &event) {
+		return true
+	}
+
+	printEvent(newTcpEventPayload(&event))
+	return true
+}
@@ -2,6 +2,7 @@ package filter
 
 import (
 	"net"
+	"path"
 	"strconv"
 	"strings"
 
@@ -52,6 +53,64 @@ func (f *ContainerNameFilter) Match(e EventPayload) bool {
 	return strings.Contains(e.ConatinerName, f.keyword)
 }
 
+// HostFilter matches the resolved host name. A leading "*." matches any
+// subdomain, e.g. "*.internal.corp" matches "db.internal.corp".
+type HostFilter struct {
+	host string
+}
+func (f *HostFilter) Match(e EventPayload) bool {
+	host := strings.ToLower(strings.TrimSuffix(e.Host, "."))
+	if host == "" {
+		return false
+	}
+	pattern := strings.ToLower(f.host)
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
+
+// PathFilter matches the unix socket path with a shell pattern as in
+// path.Match, e.g. "/run/containerd/*". Abstract sockets start with "@".
+type PathFilter struct {
+	pattern string
+}
+func (f *PathFilter) Match(e EventPayload) bool {
+	if e.SocketPath == "" {
+		return false
+	}
+	ok, _ := path.Match(f.pattern, e.SocketPath)
+	return ok
+}
+
+type ProtocolFilter struct {
+	protocol string
+}
+func (f *ProtocolFilter) Match(e EventPayload) bool {
+	return strings.EqualFold(e.Protocol, f.protocol)
+}
+
+type DirectionFilter struct {
+	direction string
+}
+func (f *DirectionFilter) Match(e EventPayload) bool {
+	return strings.EqualFold(e.Direction, f.direction)
+}
+
+type StateFilter struct {
+	state string
+}
+func (f *StateFilter) Match(e EventPayload) bool {
+	return strings.EqualFold(e.State, f.state)
+}
+
+type ResultFilter struct {
+	result string
+}
+func (f *ResultFilter) Match(e EventPayload) bool {
+	return strings.EqualFold(e.Result, f.result)
+}
+
 
 type FilterGroup struct {
 	filters []FilterCondition
@@ -92,6 +151,37 @@ func (ef *ExcludeFilter) ShouldExclude(e EventPayload) bool {
 	return false
 }
 
+// KernelExclusions returns the dport and dip/CIDR conditions that exclude an
+// event on their own, i.e. the members of "||" groups and single condition
+// groups. These can be matched in the kernel, all other conditions are only
+// evaluated by ShouldExclude. IP addresses are returned as host networks.
+func (ef *ExcludeFilter) KernelExclusions() (ports []uint16, nets []*net.IPNet) {
+	for _, group := range ef.groups {
+		if group.op == "&&" && len(group.filters) > 1 {
+			continue
+		}
+		for _, filter := range group.filters {
+			switch f := filter.(type) {
+			case *PortFilter:
+				ports = append(ports, f.port)
+			case *CIDRFilter:
+				nets = append(nets, f.ipNet)
+			case *IPFilter:
+				ip := net.ParseIP(f.ip)
+				if ip == nil {
+					continue
+				}
+				bits := 8 * net.IPv6len
+				if ip4 := ip.To4(); ip4 != nil {
+					ip, bits = ip4, 8*net.IPv4len
+				}
+				nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			}
+		}
+	}
+	return ports, nets
+}
+
 func ParseExcludeParam(param string) *ExcludeFilter {
 	ef := &ExcludeFilter{}
 	
@@ -145,6 +235,18 @@ func ParseExcludeParam(param string) *ExcludeFilter {
 				filters = append(filters, &KeywordFilter{keyword: value})
 			case "container":
 				filters = append(filters, &ContainerNameFilter{keyword: value})
+			case "result":
+				filters = append(filters, &ResultFilter{result: value})
+			case "state":
+				filters = append(filters, &StateFilter{state: value})
+			case "direction":
+				filters = append(filters, &DirectionFilter{direction: value})
+			case "proto":
+				filters = append(filters, &ProtocolFilter{protocol: value})
+			case "host":
+				filters = append(filters, &HostFilter{host: value})
+			case "path":
+				filters = append(filters, &PathFilter{pattern: value})
 			}
 		}
 		
@@ -2,6 +2,7 @@ package filter
 
 import (
 	"net"
+	"reflect"
 	"testing"
 
 	. "github.com/gotoolkits/lightmon/event"
@@ -149,6 +150,264 @@ func TestKeywordFilter_Match(t *testing.T) {
 	}
 }
 
+func TestHostFilter_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		event    EventPayload
+		expected bool
+	}{
+		{
+			name: "match exact host",
+			host: "db.internal.corp",
+			event: EventPayload{
+				Host: "DB.internal.corp",
+			},
+			expected: true,
+		},
+		{
+			name: "match suffix",
+			host: "*.internal.corp",
+			event: EventPayload{
+				Host: "db.eu.internal.corp",
+			},
+			expected: true,
+		},
+		{
+			name: "suffix does not match parent domain",
+			host: "*.internal.corp",
+			event: EventPayload{
+				Host: "internal.corp",
+			},
+			expected: false,
+		},
+		{
+			name: "suffix matches on label boundary",
+			host: "*.internal.corp",
+			event: EventPayload{
+				Host: "notinternal.corp",
+			},
+			expected: false,
+		},
+		{
+			name: "empty host",
+			host: "*.internal.corp",
+			event: EventPayload{
+				Host: "",
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &HostFilter{host: tt.host}
+			if got := f.Match(tt.event); got != tt.expected {
+				t.Errorf("HostFilter.Match() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPathFilter_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		event    EventPayload
+		expected bool
+	}{
+		{
+			name:    "match exact path",
+			pattern: "/var/run/docker.sock",
+			event: EventPayload{
+				SocketPath: "/var/run/docker.sock",
+			},
+			expected: true,
+		},
+		{
+			name:    "match pattern",
+			pattern: "/run/containerd/*.sock",
+			event: EventPayload{
+				SocketPath: "/run/containerd/containerd.sock",
+			},
+			expected: true,
+		},
+		{
+			name:    "match abstract socket",
+			pattern: "@/tmp/.X11-unix/*",
+			event: EventPayload{
+				SocketPath: "@/tmp/.X11-unix/X0",
+			},
+			expected: true,
+		},
+		{
+			name:    "pattern does not cross directories",
+			pattern: "/run/*",
+			event: EventPayload{
+				SocketPath: "/run/containerd/containerd.sock",
+			},
+			expected: false,
+		},
+		{
+			name:    "no socket path",
+			pattern: "*",
+			event: EventPayload{
+				DestPort: 443,
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &PathFilter{pattern: tt.pattern}
+			if got := f.Match(tt.event); got != tt.expected {
+				t.Errorf("PathFilter.Match() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProtocolFilter_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		event    EventPayload
+		expected bool
+	}{
+		{
+			name:     "match protocol",
+			protocol: "UDP",
+			event: EventPayload{
+				Protocol: ProtocolUDP,
+			},
+			expected: true,
+		},
+		{
+			name:     "not match protocol",
+			protocol: "udp",
+			event: EventPayload{
+				Protocol: ProtocolTCP,
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &ProtocolFilter{protocol: tt.protocol}
+			if got := f.Match(tt.event); got != tt.expected {
+				t.Errorf("ProtocolFilter.Match() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDirectionFilter_Match(t *testing.T) {
+	tests := []struct {
+		name      string
+		direction string
+		event     EventPayload
+		expected  bool
+	}{
+		{
+			name:      "match direction",
+			direction: "Inbound",
+			event: EventPayload{
+				Direction: DirectionInbound,
+			},
+			expected: true,
+		},
+		{
+			name:      "not match direction",
+			direction: "inbound",
+			event: EventPayload{
+				Direction: DirectionOutbound,
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &DirectionFilter{direction: tt.direction}
+			if got := f.Match(tt.event); got != tt.expected {
+				t.Errorf("DirectionFilter.Match() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStateFilter_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    string
+		event    EventPayload
+		expected bool
+	}{
+		{
+			name:  "match state",
+			state: "established",
+			event: EventPayload{
+				State: "ESTABLISHED",
+			},
+			expected: true,
+		},
+		{
+			name:  "not match state",
+			state: "ESTABLISHED",
+			event: EventPayload{
+				State: "CLOSE",
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &StateFilter{state: tt.state}
+			if got := f.Match(tt.event); got != tt.expected {
+				t.Errorf("StateFilter.Match() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResultFilter_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   string
+		event    EventPayload
+		expected bool
+	}{
+		{
+			name:   "match result",
+			result: "econnrefused",
+			event: EventPayload{
+				Result: "ECONNREFUSED",
+			},
+			expected: true,
+		},
+		{
+			name:   "not match result",
+			result: "ECONNREFUSED",
+			event: EventPayload{
+				Result: "OK",
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &ResultFilter{result: tt.result}
+			if got := f.Match(tt.event); got != tt.expected {
+				t.Errorf("ResultFilter.Match() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestExcludeFilter_ShouldExclude(t *testing.T) {
 	_, ipNet, _ := net.ParseCIDR("192.168.1.0/24")
 	
@@ -276,6 +535,57 @@ func TestParseExcludeParam(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name:  "host condition",
+			param: "host='*.internal.corp'",
+			event: EventPayload{
+				Host: "git.internal.corp",
+			},
+			expected: true,
+		},
+		{
+			name:  "path condition",
+			param: "path='/run/systemd/journal/*'",
+			event: EventPayload{
+				SocketPath: "/run/systemd/journal/stdout",
+			},
+			expected: true,
+		},
+		{
+			name:  "protocol condition",
+			param: "proto='udp' && dport=53",
+			event: EventPayload{
+				Protocol: ProtocolUDP,
+				DestPort: 53,
+			},
+			expected: true,
+		},
+		{
+			name:  "direction condition",
+			param: "direction='outbound' && dport=22",
+			event: EventPayload{
+				Direction: DirectionOutbound,
+				DestPort:  22,
+			},
+			expected: true,
+		},
+		{
+			name:  "state condition",
+			param: "state='CLOSE'",
+			event: EventPayload{
+				State: "CLOSE",
+			},
+			expected: true,
+		},
+		{
+			name:  "result condition",
+			param: "result='EINPROGRESS' && dport=443",
+			event: EventPayload{
+				DestPort: 443,
+				Result:   "EINPROGRESS",
+			},
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -286,4 +596,49 @@ func TestParseExcludeParam(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestExcludeFilter_KernelExclusions(t *testing.T) {
+	tests := []struct {
+		name      string
+		param     string
+		wantPorts []uint16
+		wantNets  []string
+	}{
+		{
+			name:      "or group",
+			param:     "keyword='qcloud'||dport='53'",
+			wantPorts: []uint16{53},
+		},
+		{
+			name:      "separate groups",
+			param:     "dport=80;dip=\"10.0.0.0/8\";dip='192.168.1.1';dip='fd00::1'",
+			wantPorts: []uint16{80},
+			wantNets:  []string{"10.0.0.0/8", "192.168.1.1/32", "fd00::1/128"},
+		},
+		{
+			name:     "single condition and group",
+			param:    "dip='10.0.0.0/8' && dport=8080; dip='172.16.0.0/12'",
+			wantNets: []string{"172.16.0.0/12"},
+		},
+		{
+			name:  "no pushable conditions",
+			param: "container='nginx';result='OK'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ports, nets := ParseExcludeParam(tt.param).KernelExclusions()
+			if !reflect.DeepEqual(ports, tt.wantPorts) {
+				t.Errorf("KernelExclusions() ports = %v, want %v", ports, tt.wantPorts)
+			}
+			var gotNets []string
+			for _, n := range nets {
+				gotNets = append(gotNets, n.String())
+			}
+			if !reflect.DeepEqual(gotNets, tt.wantNets) {
+				t.Errorf("KernelExclusions() nets = %v, want %v", gotNets, tt.wantNets)
+			}
+		})
+	}
+}
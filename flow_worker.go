@@ -0,0 +1,155 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/gotoolkits/lightmon/conv"
+	. "github.com/gotoolkits/lightmon/event"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+	"golang.org/x/sys/unix"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 flow tcpFlowSrc.c -- -Iheaders/
+
+func setupBpfFlowWorkers() {
+	if !TcpState_Runtime_Verifier() {
+		log.Fatalln("sock:inet_sock_set_state tracepoint is not supported")
+	}
+	if !haveKernelTypes() {
+		log.Fatalf("flow records need kernel BTF, %s not found, use -btf to load it from a file", KERNEL_BTF)
+	}
+
+	// Load pre-compiled programs and maps into the kernel.
+	objs := flowObjects{}
+	if err := loadFlowObjects(&objs, collectionOptions()); err != nil {
+		log.Fatalf("loading flow objects: %v", err)
+	}
+	addCloser(&objs)
+	loadKernelExclusions(objs.ExcludedPorts, objs.ExcludedNets)
+
+	tp, err := link.Tracepoint("sock", "inet_sock_set_state", objs.FlowSetState, nil)
+	if err != nil {
+		log.Fatalf("attaching tracepoint: %s", err)
+	}
+	addCloser(tp)
+
+	probes := []struct {
+		symbol string
+		prog   *ebpf.Program
+		ret    bool
+	}{
+		{"tcp_sendmsg", objs.TcpSendmsg, false},
+		{"tcp_sendmsg", objs.TcpSendmsgRet, true},
+		{"tcp_recvmsg", objs.TcpRecvmsg, false},
+		{"tcp_recvmsg", objs.TcpRecvmsgRet, true},
+	}
+	for _, p := range probes {
+		var kp link.Link
+		if p.ret {
+			kp, err = link.Kretprobe(p.symbol, p.prog, nil)
+		} else {
+			kp, err = link.Kprobe(p.symbol, p.prog, nil)
+		}
+		if err != nil {
+			log.Fatalf("attaching kprobe %s: %s", p.symbol, err)
+		}
+		addCloser(kp)
+	}
+
+	rd := newPerfReader("flow", objs.FlowEvents)
+
+	go (func() {
+		for {
+			if !readFlowEvents(rd) {
+				return
+			}
+		}
+	})()
+}
+
+func readFlowEvents(rd *perf.Reader) bool {
+	var event FlowEvent
+	sample, ok := readPerfSample(rd, "flow")
+	if !ok {
+		return false
+	}
+	if sample == nil || !decodeSample("flow", sample, &event) {
+		return true
+	}
+
+	printEvent(newFlowEventPayload(&event))
+	return true
+}
+
+func newFlowEventPayload(event *FlowEvent) EventPayload {
+	tcpEvent := TcpEvent{
+		Comm:   event.Comm,
+		Pid:    event.Pid,
+		Uid:    event.Uid,
+		Sport:  event.Sport,
+		Dport:  event.Dport,
+		Af:     event.Af,
+		Saddr:  event.Saddr,
+		Daddr:  event.Daddr,
+		TsUs:   event.TsUs,
+		Saddr6: event.Saddr6,
+		Daddr6: event.Daddr6,
+
+		TaskIDs: event.TaskIDs,
+	}
+	if event.Inbound != 0 {
+		swapTcpEventAddrs(&tcpEvent)
+	}
+
+	var eventPayload EventPayload
+	if event.Pid == 0 {
+		// Nothing was sent or received in process context.
+		eventPayload = EventPayload{
+			UTime:         time.Now(),
+			AddressFamily: conv.ToAddressFamily(int(event.Af)),
+			EbpfType:      ebpfType.String(),
+		}
+		setTcpEventAddrs(&eventPayload, &tcpEvent)
+	} else {
+		eventPayload = newTcpEventPayload(&tcpEvent)
+	}
+
+	eventPayload.Type = TypeFlow
+	eventPayload.Protocol = ProtocolTCP
+	eventPayload.Direction = DirectionOutbound
+	if event.Inbound != 0 {
+		eventPayload.Direction = DirectionInbound
+	}
+	eventPayload.Result = ""
+	eventPayload.Errno = 0
+
+	start := ktimeToTime(event.StartUs)
+	end := ktimeToTime(event.TsUs)
+	eventPayload.Flow = &Flow{
+		StartTime:  start,
+		EndTime:    end,
+		DurationMs: end.Sub(start).Milliseconds(),
+		TxBytes:    event.TxBytes,
+		RxBytes:    event.RxBytes,
+		TxPackets:  event.TxPackets,
+		RxPackets:  event.RxPackets,
+	}
+	return eventPayload
+}
+
+// ktimeToTime converts a bpf_ktime_get_ns timestamp in microseconds, i.e.
+// CLOCK_MONOTONIC, to wall clock time.
+func ktimeToTime(us uint64) time.Time {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return time.Now()
+	}
+	return time.Now().Add(time.Duration(us)*time.Microsecond - time.Duration(ts.Nano()))
+}
@@ -0,0 +1,57 @@
+// Kernel side of the exclude filter. The dport and dip/CIDR conditions that
+// alone exclude an event are loaded into these maps by userspace, so
+// matching events are dropped before they are sent to userspace.
+//
+// Include after common.h or vmlinux_compact_common.h and bpf_helpers.h.
+
+#ifndef __EXCLUDE_H__
+#define __EXCLUDE_H__
+
+#ifndef BPF_F_NO_PREALLOC
+#define BPF_F_NO_PREALLOC (1U << 0)
+#endif
+
+// exclude_net_key holds an IPv6 address, IPv4 addresses are mapped to
+// ::ffff:a.b.c.d so both families share one trie.
+struct exclude_net_key {
+    __u32 prefixlen;
+    __u8 addr[16];
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 1024);
+    __type(key, __u16);
+    __type(value, __u8);
+} excluded_ports SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_LPM_TRIE);
+    __uint(max_entries, 1024);
+    __type(key, struct exclude_net_key);
+    __type(value, __u8);
+    __uint(map_flags, BPF_F_NO_PREALLOC);
+} excluded_nets SEC(".maps");
+
+// is_excluded reports whether an event with the given destination is
+// excluded. af is AF_INET (2) or AF_INET6 (10), dport is in host order.
+static __always_inline int is_excluded(__u16 af, __be32 daddr, const __u8 *daddr6, __u16 dport) {
+    if (bpf_map_lookup_elem(&excluded_ports, &dport)) {
+        return 1;
+    }
+
+    struct exclude_net_key key = {};
+    key.prefixlen = 128;
+    if (af == 2) {
+        key.addr[10] = 0xff;
+        key.addr[11] = 0xff;
+        __builtin_memcpy(&key.addr[12], &daddr, 4);
+    } else if (af == 10) {
+        __builtin_memcpy(key.addr, daddr6, 16);
+    } else {
+        return 0;
+    }
+    return bpf_map_lookup_elem(&excluded_nets, &key) != 0;
+}
+
+#endif /* __EXCLUDE_H__ */
@@ -0,0 +1,81 @@
+// Kernel side of the -scope_* options. Programs call in_scope() first and
+// drop everything the current task does when it returns 0. scope_flags and
+// the PID namespace are set by userspace before loading, so the checks that
+// are not enabled are pruned by the verifier and their helpers are only
+// required when used.
+//
+// Include after common.h or vmlinux_compact_common.h and bpf_helpers.h.
+
+#ifndef __SCOPE_H__
+#define __SCOPE_H__
+
+#define SCOPE_CGROUP 1
+#define SCOPE_PIDNS 2
+#define SCOPE_COMM 4
+
+// Deepest cgroup level checked against scope_cgroups.
+#define SCOPE_MAX_CGROUP_DEPTH 16
+
+struct bpf_pidns_info {
+    __u32 pid;
+    __u32 tgid;
+};
+
+const volatile __u32 scope_flags = 0;
+const volatile __u64 scope_pidns_dev = 0;
+const volatile __u64 scope_pidns_ino = 0;
+
+// scope_cgroups holds cgroup v2 IDs. A task is in scope when its cgroup or
+// any of its ancestors below the root is in the map.
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 1024);
+    __type(key, __u64);
+    __type(value, __u8);
+} scope_cgroups SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 256);
+    __type(key, char[16]);
+    __type(value, __u8);
+} scope_comms SEC(".maps");
+
+// in_scope reports whether the current task matches any enabled scope. It
+// returns 1 when no scope is set.
+static __always_inline int in_scope(void) {
+    if (!scope_flags) {
+        return 1;
+    }
+
+    if (scope_flags & SCOPE_COMM) {
+        char comm[16] = {};
+        bpf_get_current_comm(comm, sizeof(comm));
+        if (bpf_map_lookup_elem(&scope_comms, comm)) {
+            return 1;
+        }
+    }
+
+    if (scope_flags & SCOPE_PIDNS) {
+        struct bpf_pidns_info ns = {};
+        if (bpf_get_ns_current_pid_tgid(scope_pidns_dev, scope_pidns_ino, &ns, sizeof(ns)) == 0) {
+            return 1;
+        }
+    }
+
+    if (scope_flags & SCOPE_CGROUP) {
+#pragma unroll
+        for (int level = 1; level < SCOPE_MAX_CGROUP_DEPTH; level++) {
+            __u64 id = bpf_get_current_ancestor_cgroup_id(level);
+            if (!id) {
+                break;
+            }
+            if (bpf_map_lookup_elem(&scope_cgroups, &id)) {
+                return 1;
+            }
+        }
+    }
+    return 0;
+}
+
+#endif /* __SCOPE_H__ */
@@ -0,0 +1,117 @@
+// Connection summaries. With -summary the connect programs don't submit an
+// event per connect, they count connects per cgroup, process, destination and
+// protocol in conn_summary, which userspace drains periodically.
+//
+// Include after bpf_helpers.h and task_ids.h.
+
+#ifndef __SUMMARY_H__
+#define __SUMMARY_H__
+
+#define SUMMARY_EEXIST 17
+#define SUMMARY_EINPROGRESS 115
+
+struct summary_key {
+    __u64 cgroup_id;
+    __u32 pid;
+    __u16 dport;
+    __u8 proto;
+    __u8 af;
+    __u8 daddr[16]; // AF_INET addresses in the first 4 bytes
+};
+
+struct summary_value {
+    __u64 count;
+    __u64 failed;   // connects that failed, EINPROGRESS is not a failure
+    __u64 first_ns; // bpf_ktime_get_ns() of the first connect
+    __u64 last_ns;  // bpf_ktime_get_ns() of the last connect
+    __u8 comm[16];
+    __u32 uid;
+    __u32 pad;
+    struct task_ids ids; // of the last connect
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 65536);
+    __type(key, struct summary_key);
+    __type(value, struct summary_value);
+} conn_summary SEC(".maps");
+
+// summary_drops counts, per CPU, the connects that found conn_summary full.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, __u32);
+    __type(value, __u64);
+} summary_drops SEC(".maps");
+
+// summary_config[0] is 1 with -summary, set when loading. It is a map rather
+// than a global so the kprobe program still loads on kernels without global
+// data.
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, __u32);
+    __type(value, __u32);
+} summary_config SEC(".maps");
+
+static __always_inline int summary_mode(void) {
+    __u32 zero = 0;
+    __u32 *on = bpf_map_lookup_elem(&summary_config, &zero);
+    return on && *on;
+}
+
+static __always_inline void summary_add(__u16 af, __be32 daddr, const __u8 *daddr6, __u16 dport,
+                                        __u8 proto, __u32 pid, __u32 uid, const __u8 *comm,
+                                        const struct task_ids *ids, __s32 ret) {
+    struct summary_key key = {};
+    key.cgroup_id = ids->cgroup_id;
+    key.pid = pid;
+    key.dport = dport;
+    key.proto = proto;
+    key.af = af;
+    if (daddr6) {
+        __builtin_memcpy(key.daddr, daddr6, 16);
+    } else {
+        __builtin_memcpy(key.daddr, &daddr, 4);
+    }
+
+    __u64 now = bpf_ktime_get_ns();
+    __u64 failed = ret < 0 && ret != -SUMMARY_EINPROGRESS;
+
+    struct summary_value *v = bpf_map_lookup_elem(&conn_summary, &key);
+    if (!v) {
+        struct summary_value nv = {};
+        nv.count = 1;
+        nv.failed = failed;
+        nv.first_ns = now;
+        nv.last_ns = now;
+        nv.uid = uid;
+        nv.ids = *ids;
+        __builtin_memcpy(nv.comm, comm, 16);
+
+        long err = bpf_map_update_elem(&conn_summary, &key, &nv, BPF_NOEXIST);
+        if (!err) {
+            return;
+        }
+        // Another CPU added the key first, count on its entry.
+        v = err == -SUMMARY_EEXIST ? bpf_map_lookup_elem(&conn_summary, &key) : 0;
+        if (!v) {
+            __u32 zero = 0;
+            __u64 *drops = bpf_map_lookup_elem(&summary_drops, &zero);
+            if (drops) {
+                *drops += 1;
+            }
+            return;
+        }
+    }
+
+    __sync_fetch_and_add(&v->count, 1);
+    if (failed) {
+        __sync_fetch_and_add(&v->failed, 1);
+    }
+    v->last_ns = now;
+    v->ids = *ids;
+}
+
+#endif /* __SUMMARY_H__ */
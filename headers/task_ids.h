@@ -0,0 +1,100 @@
+// Identifies the current task, its parent, container and namespaces in the
+// kernel, so events can be attributed even when the process is gone, or its
+// PID reused, by the time userspace reads them.
+//
+// The fields read from task_struct need CO-RE and therefore kernel BTF.
+// Objects that already require BTF define TASK_IDS_CORE before including
+// this header, the others only record the cgroup ID and thread ID and leave
+// the rest 0.
+//
+// Include after common.h or vmlinux_compact_common.h and bpf_helpers.h.
+// bpf_core_read.h is included here when TASK_IDS_CORE is defined.
+
+#ifndef __TASK_IDS_H__
+#define __TASK_IDS_H__
+
+struct task_ids {
+    __u64 cgroup_id; // cgroup v2 ID
+    __u64 start_ns;  // process start time, ns since boot
+    __u32 netns;     // network namespace inode
+    __u32 pidns;     // PID namespace inode
+    __u32 tid;       // thread ID
+    __u32 ppid;      // TGID of the parent process
+};
+
+#ifdef TASK_IDS_CORE
+#include "bpf_core_read.h"
+
+struct ns_common {
+    unsigned int inum;
+} __attribute__((preserve_access_index));
+
+struct net {
+    struct ns_common ns;
+} __attribute__((preserve_access_index));
+
+struct pid_namespace {
+    struct ns_common ns;
+} __attribute__((preserve_access_index));
+
+struct nsproxy {
+    struct pid_namespace *pid_ns_for_children;
+    struct net *net_ns;
+} __attribute__((preserve_access_index));
+
+struct upid {
+    int nr;
+    struct pid_namespace *ns;
+} __attribute__((preserve_access_index));
+
+struct pid {
+    unsigned int level;
+    struct upid numbers[1];
+} __attribute__((preserve_access_index));
+
+struct task_struct {
+    int tgid;
+    struct task_struct *group_leader;
+    struct task_struct *real_parent;
+    struct nsproxy *nsproxy;
+    struct pid *thread_pid; // since Linux 4.19
+    __u64 start_boottime;
+    __u64 real_start_time; // start_boottime before Linux 5.5
+} __attribute__((preserve_access_index));
+#endif
+
+static __always_inline struct task_ids current_task_ids(void) {
+    struct task_ids ids = {};
+    ids.cgroup_id = bpf_get_current_cgroup_id();
+    ids.tid = (__u32)bpf_get_current_pid_tgid();
+
+#ifdef TASK_IDS_CORE
+    struct task_struct *task = (struct task_struct *)bpf_get_current_task();
+
+    // The start time of the process is the one of its main thread, as
+    // in /proc/<pid>/stat.
+    if (bpf_core_field_exists(task->start_boottime)) {
+        ids.start_ns = BPF_CORE_READ(task, group_leader, start_boottime);
+    } else {
+        ids.start_ns = BPF_CORE_READ(task, group_leader, real_start_time);
+    }
+    ids.ppid = BPF_CORE_READ(task, real_parent, tgid);
+    ids.netns = BPF_CORE_READ(task, nsproxy, net_ns, ns.inum);
+    // The namespace task_active_pid_ns() returns, the one the task's PID
+    // was allocated in at its deepest level, as bpf_get_ns_current_pid_tgid
+    // sees it. Before Linux 4.19 the namespace for children is the closest,
+    // which differs only after unshare(CLONE_NEWPID).
+    if (bpf_core_field_exists(task->thread_pid)) {
+        struct pid *pid = BPF_CORE_READ(task, thread_pid);
+        unsigned int level = BPF_CORE_READ(pid, level);
+        struct upid upid = {};
+        bpf_core_read(&upid, sizeof(upid), &pid->numbers[level]);
+        ids.pidns = BPF_CORE_READ(upid.ns, ns.inum);
+    } else {
+        ids.pidns = BPF_CORE_READ(task, nsproxy, pid_ns_for_children, ns.inum);
+    }
+#endif
+    return ids;
+}
+
+#endif /* __TASK_IDS_H__ */
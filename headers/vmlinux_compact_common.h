@@ -104,6 +104,10 @@ struct sockaddr_in {
 	unsigned char __pad[8];
 };
 
+// sock_common mirrors the kernel layout on 64-bit architectures built with
+// CONFIG_IPV6, padding out the fields that are not accessed. Only the kprobe
+// program uses it, on kernels with BTF its offsets are checked against
+// compactSockCommon in kernel_btf.go.
 struct sock_common {
     union {
         struct {
@@ -111,6 +115,10 @@ struct sock_common {
             __be32 skc_rcv_saddr;
         };
     };
+    union {
+        unsigned int skc_hash;
+        __u16 skc_u16hashes[2];
+    };
     union {
         struct {
             __be16 skc_dport;
@@ -118,6 +126,9 @@ struct sock_common {
         };
     };
     unsigned short skc_family;
+    // skc_state, skc_reuse..skc_net_refcnt, skc_bound_dev_if,
+    // skc_bind_node, skc_prot and skc_net.
+    __u8 __pad[38];
     struct in6_addr skc_v6_daddr;
     struct in6_addr skc_v6_rcv_saddr;
 };
@@ -126,6 +137,19 @@ struct sock {
     struct sock_common __sk_common;
 };
 
+// tcp_sock is relocated with CO-RE, so only the fields that are read need
+// to be declared. Programs using it need kernel BTF.
+struct tcp_sock {
+    __u32 segs_in;
+    __u32 segs_out;
+} __attribute__((preserve_access_index));
+
+// Only the leading fields of msghdr are needed, their offsets never changed.
+struct msghdr {
+    void *msg_name;
+    int msg_namelen;
+};
+
 struct inet_sock {
     struct sock sk;
     struct {
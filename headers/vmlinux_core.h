@@ -0,0 +1,137 @@
+// Kernel types for the programs built with CO-RE, and the tracepoint ABI.
+//
+// Only the fields that are accessed are declared. preserve_access_index
+// makes clang record every access as a relocation, which the loader resolves
+// against the BTF of the running kernel (or the file given with -btf), so the
+// offsets no longer have to match a particular kernel build.
+//
+// Include after common.h.
+
+#ifndef __VMLINUX_CORE_H__
+#define __VMLINUX_CORE_H__
+
+struct in6_addr {
+    union {
+        __u8 u6_addr8[16];
+        __be16 u6_addr16[8];
+        __be32 u6_addr32[4];
+    } in6_u;
+} __attribute__((preserve_access_index));
+
+struct sock_common {
+    union {
+        struct {
+            __be32 skc_daddr;
+            __be32 skc_rcv_saddr;
+        };
+    };
+    union {
+        struct {
+            __be16 skc_dport;
+            __u16 skc_num;
+        };
+    };
+    short unsigned int skc_family;
+    struct in6_addr skc_v6_daddr;     // only with CONFIG_IPV6
+    struct in6_addr skc_v6_rcv_saddr; // only with CONFIG_IPV6
+} __attribute__((preserve_access_index));
+
+struct sock {
+    struct sock_common __sk_common;
+} __attribute__((preserve_access_index));
+
+struct socket {
+    struct sock *sk;
+} __attribute__((preserve_access_index));
+
+struct iovec;
+
+// ITER_UBUF exists since Linux 6.0, its value is relocated.
+enum iter_type {
+    ITER_UBUF = 0,
+};
+
+// The anonymous unions around the iterator fields are matched by CO-RE.
+struct iov_iter {
+    __u8 iter_type;            // since Linux 5.14
+    unsigned long count;
+    const struct iovec *__iov; // since Linux 6.4
+    const struct iovec *iov;   // before Linux 6.4
+    void *ubuf;                // since Linux 6.0
+} __attribute__((preserve_access_index));
+
+struct msghdr {
+    void *msg_name;
+    struct iov_iter msg_iter;
+} __attribute__((preserve_access_index));
+
+// Context of the syscalls:sys_enter_* and syscalls:sys_exit_* tracepoints.
+// The layout is the tracepoint ABI (see their format files in tracefs) and
+// is not relocated, so the programs using it load without BTF.
+struct trace_entry {
+    short unsigned int type;
+    unsigned char flags;
+    unsigned char preempt_count;
+    int pid;
+};
+
+struct trace_event_raw_sys_enter {
+    struct trace_entry ent;
+    long int id;
+    long unsigned int args[6];
+};
+
+struct trace_event_raw_sys_exit {
+    struct trace_entry ent;
+    long int id;
+    long int ret;
+};
+
+// Context of the sock:inet_sock_set_state tracepoint, also the tracepoint
+// ABI.
+struct trace_event_raw_inet_sock_set_state {
+    struct trace_entry ent;
+    const void *skaddr;
+    int oldstate;
+    int newstate;
+    __u16 sport;
+    __u16 dport;
+    __u16 family;
+    __u16 protocol;
+    __u8 saddr[4];
+    __u8 daddr[4];
+    __u8 saddr_v6[16];
+    __u8 daddr_v6[16];
+    char __data[0];
+};
+
+// The socket addresses are read from user memory. Their layout is part of
+// the UAPI and never changes, so they are not relocated.
+typedef short unsigned int __kernel_sa_family_t;
+typedef __kernel_sa_family_t sa_family_t;
+
+struct sockaddr {
+    sa_family_t sa_family;
+    char sa_data[14];
+};
+
+struct in_addr {
+    __be32 s_addr;
+};
+
+struct sockaddr_in {
+    __kernel_sa_family_t sin_family;
+    __be16 sin_port;
+    struct in_addr sin_addr;
+    unsigned char __pad[8];
+};
+
+struct sockaddr_in6 {
+    short unsigned int sin6_family;
+    __be16 sin6_port;
+    __be32 sin6_flowinfo;
+    struct in6_addr sin6_addr;
+    __u32 sin6_scope_id;
+};
+
+#endif /* __VMLINUX_CORE_H__ */
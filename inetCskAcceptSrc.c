@@ -0,0 +1,89 @@
+// +build ignore
+
+#include "common.h"
+#include "vmlinux_core.h"
+
+// common.h only declares the x86 registers.
+#if defined(__TARGET_ARCH_arm64)
+#include "vmlinux_compact_arm64.h"
+#endif
+
+#include "bpf_tracing.h"
+#include "bpf_endian.h"
+#include "bpf_core_read.h"
+#include "exclude.h"
+#include "task_ids.h"
+
+#define TASK_COMM_LEN 16
+#define AF_INET 2
+#define AF_INET6 10
+
+char LICENSE[] SEC("license") = "Dual MIT/GPL";
+
+/**
+ * struct event has the same layout as the one in fentryTcpConnectSrc.c, so
+ * accepted connections are decoded into event.TcpEvent as well. For inbound
+ * connections saddr/sport hold the remote peer and daddr/dport the local
+ * address the peer connected to.
+ */
+struct event {
+    u8 comm[TASK_COMM_LEN];
+    __u32 pid;
+    __u32 uid;
+    __u16 sport;
+    __u16 dport;
+    __u16 af;
+    __u16 pad;
+    __be32 saddr;
+    __be32 daddr;
+    __u64 ts_us;
+    __u8 saddr6[16];
+    __u8 daddr6[16];
+    struct task_ids ids;
+    __s32 ret;
+    __u32 pad2;
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+} accept_events SEC(".maps");
+
+/**
+ * inet_csk_accept returns the new child socket, or NULL on error. Its
+ * arguments changed across kernel versions but the return value did not,
+ * so a kretprobe works on every kernel lightmon supports.
+ */
+SEC("kretprobe/inet_csk_accept")
+int BPF_KRETPROBE(inet_csk_accept_ret, struct sock *newsk) {
+    if (!newsk) {
+        return 0;
+    }
+
+    struct event tcp_info = {};
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    tcp_info.ts_us = bpf_ktime_get_ns() / 1000;
+    tcp_info.pid = pid_tgid >> 32;
+    tcp_info.uid = (u32)bpf_get_current_uid_gid();
+    tcp_info.af = BPF_CORE_READ(newsk, __sk_common.skc_family);
+    tcp_info.sport = bpf_ntohs(BPF_CORE_READ(newsk, __sk_common.skc_dport));
+    tcp_info.dport = BPF_CORE_READ(newsk, __sk_common.skc_num);
+    if (tcp_info.af == AF_INET) {
+        tcp_info.saddr = BPF_CORE_READ(newsk, __sk_common.skc_daddr);
+        tcp_info.daddr = BPF_CORE_READ(newsk, __sk_common.skc_rcv_saddr);
+    } else if (tcp_info.af == AF_INET6 && bpf_core_field_exists(newsk->__sk_common.skc_v6_daddr)) {
+        BPF_CORE_READ_INTO(&tcp_info.saddr6, newsk, __sk_common.skc_v6_daddr.in6_u.u6_addr8);
+        BPF_CORE_READ_INTO(&tcp_info.daddr6, newsk, __sk_common.skc_v6_rcv_saddr.in6_u.u6_addr8);
+    } else {
+        return 0;
+    }
+    if (is_excluded(tcp_info.af, tcp_info.daddr, tcp_info.daddr6, tcp_info.dport)) {
+        return 0;
+    }
+    bpf_get_current_comm(&tcp_info.comm, TASK_COMM_LEN);
+    tcp_info.ids = current_task_ids();
+
+    bpf_perf_event_output(ctx, &accept_events, BPF_F_CURRENT_CPU, &tcp_info, sizeof(tcp_info));
+    return 0;
+}
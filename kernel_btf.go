@@ -0,0 +1,111 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+// kernelTypes is the BTF loaded from -btf. When nil, CO-RE relocations are
+// resolved against the running kernel's /sys/kernel/btf/vmlinux.
+var kernelTypes *btf.Spec
+
+// loadKernelTypes reads the BTF of the running kernel from an external file,
+// for kernels built without CONFIG_DEBUG_INFO_BTF, e.g. one from BTFHub.
+func loadKernelTypes(path string) {
+	spec, err := btf.LoadSpec(path)
+	if err != nil {
+		log.Fatalf("loading BTF from %s: %v", path, err)
+	}
+	kernelTypes = spec
+}
+
+// haveKernelTypes reports whether the programs built with CO-RE can be
+// relocated, either against the kernel's own BTF or the -btf file.
+func haveKernelTypes() bool {
+	if kernelTypes != nil {
+		return true
+	}
+	ok, _ := PathExists(KERNEL_BTF)
+	return ok
+}
+
+// collectionOptions returns the options every collection is loaded with.
+func collectionOptions() *ebpf.CollectionOptions {
+	return &ebpf.CollectionOptions{
+		Programs: ebpf.ProgramOptions{KernelTypes: kernelTypes},
+	}
+}
+
+// compactSockCommon holds the offsets of the sock_common fields read by the
+// kprobe program through headers/vmlinux_compact_common.h, which does not
+// need BTF but only matches the kernel build it was copied from.
+var compactSockCommon = map[string]uint32{
+	"skc_daddr":        0,
+	"skc_rcv_saddr":    4,
+	"skc_dport":        12,
+	"skc_num":          14,
+	"skc_family":       16,
+	"skc_v6_daddr":     56,
+	"skc_v6_rcv_saddr": 72,
+}
+
+// checkCompactSockLayout compares compactSockCommon with the kernel BTF, if
+// there is any. Without BTF the layout cannot be checked.
+func checkCompactSockLayout() error {
+	if !haveKernelTypes() {
+		return nil
+	}
+	spec := kernelTypes
+	if spec == nil {
+		var err error
+		if spec, err = btf.LoadKernelSpec(); err != nil {
+			return fmt.Errorf("loading kernel BTF: %w", err)
+		}
+	}
+	var skc *btf.Struct
+	if err := spec.TypeByName("sock_common", &skc); err != nil {
+		return fmt.Errorf("sock_common: %w", err)
+	}
+	for name, want := range compactSockCommon {
+		got, ok := memberOffset(skc, name)
+		if !ok {
+			return fmt.Errorf("sock_common.%s not found", name)
+		}
+		if got != want {
+			return fmt.Errorf("sock_common.%s is at offset %d, not %d", name, got, want)
+		}
+	}
+	return nil
+}
+
+// memberOffset returns the offset in bytes of a member of a struct or
+// union, looking into anonymous members.
+func memberOffset(typ btf.Type, name string) (uint32, bool) {
+	var members []btf.Member
+	switch t := typ.(type) {
+	case *btf.Struct:
+		members = t.Members
+	case *btf.Union:
+		members = t.Members
+	default:
+		return 0, false
+	}
+	for _, m := range members {
+		if m.Name == name {
+			return m.Offset.Bytes(), true
+		}
+		if m.Name != "" {
+			continue
+		}
+		if off, ok := memberOffset(btf.UnderlyingType(m.Type), name); ok {
+			return m.Offset.Bytes() + off, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,49 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"log"
+	"net"
+
+	"github.com/gotoolkits/lightmon/filter"
+
+	"github.com/cilium/ebpf"
+)
+
+// excludeNetKey mirrors struct exclude_net_key in headers/exclude.h.
+type excludeNetKey struct {
+	Prefixlen uint32
+	Addr      [16]byte
+}
+
+// loadKernelExclusions fills the excluded_ports and excluded_nets maps of an
+// eBPF object with the exclude conditions that can be matched in the kernel.
+// The userspace filter still evaluates every condition.
+func loadKernelExclusions(portsMap, netsMap *ebpf.Map) {
+	if config.ExcludeFilter == "" {
+		return
+	}
+	ports, nets := filter.ParseExcludeParam(config.ExcludeFilter).KernelExclusions()
+
+	one := uint8(1)
+	for _, port := range ports {
+		if err := portsMap.Put(port, one); err != nil {
+			log.Fatalf("excluding dport %d in kernel: %v", port, err)
+		}
+	}
+
+	for _, ipNet := range nets {
+		ones, bits := ipNet.Mask.Size()
+		// IPv4 networks are stored as ::ffff:a.b.c.d/96+n, see exclude.h.
+		if bits == 8*net.IPv4len {
+			ones += 96
+		}
+		key := excludeNetKey{Prefixlen: uint32(ones)}
+		copy(key.Addr[:], ipNet.IP.To16())
+		if err := netsMap.Put(key, one); err != nil {
+			log.Fatalf("excluding dip %s in kernel: %v", ipNet, err)
+		}
+	}
+}
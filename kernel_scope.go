@@ -0,0 +1,76 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/gotoolkits/lightmon/linux"
+
+	"github.com/cilium/ebpf"
+)
+
+// scope flags, see headers/scope.h
+const (
+	scopeCgroup = 1
+	scopePidns  = 2
+	scopeComm   = 4
+)
+
+// scoped reports whether any -scope_* option is set.
+func scoped() bool {
+	return config.ScopeCgroup != "" || config.ScopePidns != "" || config.ScopeComm != ""
+}
+
+// setKernelScope restricts the programs of spec that call in_scope() to the
+// tasks selected with the -scope_* options. It must be called before the
+// spec is loaded.
+func setKernelScope(spec *ebpf.CollectionSpec) {
+	var flags uint32
+	one := uint8(1)
+
+	if config.ScopeCgroup != "" {
+		ids, err := linux.CgroupIDs(config.ScopeCgroup)
+		if err != nil {
+			log.Fatalf("resolving scope cgroups: %v", err)
+		}
+		for _, id := range ids {
+			spec.Maps["scope_cgroups"].Contents = append(spec.Maps["scope_cgroups"].Contents, ebpf.MapKV{Key: id, Value: one})
+		}
+		flags |= scopeCgroup
+	}
+
+	if config.ScopePidns != "" {
+		dev, ino, err := linux.PidNamespace(config.ScopePidns)
+		if err != nil {
+			log.Fatalf("resolving scope pid namespace: %v", err)
+		}
+		if err := spec.Variables["scope_pidns_dev"].Set(dev); err != nil {
+			log.Fatalf("setting scope pid namespace: %v", err)
+		}
+		if err := spec.Variables["scope_pidns_ino"].Set(ino); err != nil {
+			log.Fatalf("setting scope pid namespace: %v", err)
+		}
+		flags |= scopePidns
+	}
+
+	if config.ScopeComm != "" {
+		for _, name := range strings.Split(config.ScopeComm, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			// The kernel keeps the first TASK_COMM_LEN-1 bytes of the name.
+			var comm [16]byte
+			copy(comm[:15], name)
+			spec.Maps["scope_comms"].Contents = append(spec.Maps["scope_comms"].Contents, ebpf.MapKV{Key: comm, Value: one})
+		}
+		flags |= scopeComm
+	}
+
+	if err := spec.Variables["scope_flags"].Set(flags); err != nil {
+		log.Fatalf("setting scope flags: %v", err)
+	}
+}
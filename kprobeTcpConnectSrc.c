@@ -0,0 +1,172 @@
+// +build ignore
+
+#include "vmlinux_compact_common.h"
+
+#if defined(__TARGET_ARCH_arm64)
+#include "vmlinux_compact_arm64.h"
+#elif defined(__TARGET_ARCH_x86)
+#include "vmlinux_compact_amd64.h"
+#endif
+
+#include "bpf_helpers.h"
+#include "bpf_tracing.h"
+#include "bpf_endian.h"
+#include "exclude.h"
+#include "task_ids.h"
+#include "summary.h"
+
+#define TASK_COMM_LEN 16
+#define AF_INET 2
+#define AF_INET6 10
+#define IPPROTO_TCP 6
+
+char LICENSE[] SEC("license") = "Dual MIT/GPL";
+
+/**
+ * This program is the fallback for kernels without BTF, or that support
+ * neither fentry nor the syscalls tracepoints. It only relies on kprobes,
+ * perf event arrays and bpf_probe_read, so the struct sock layout in
+ * vmlinux_compact_common.h must match the running kernel.
+ *
+ * struct event has the same layout as the one in fentryTcpConnectSrc.c, so
+ * both are decoded into event.TcpEvent in userspace.
+ */
+struct event {
+    u8 comm[TASK_COMM_LEN];
+    __u32 pid;
+    __u32 uid;
+    __u16 sport;
+    __u16 dport;
+    __u16 af;
+    __u16 pad;
+    __be32 saddr;
+    __be32 daddr;
+    __u64 ts_us;
+    __u8 saddr6[16];
+    __u8 daddr6[16];
+    struct task_ids ids;
+    __s32 ret;
+    __u32 pad2;
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+// sockets keeps the struct sock passed to tcp_v4_connect/tcp_v6_connect,
+// keyed by pid_tgid, until the matching kretprobe fires.
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 10240);
+    __type(key, u64);
+    __type(value, struct sock *);
+} sockets SEC(".maps");
+
+// connects holds the event built when tcp_v4_connect/tcp_v6_connect returns,
+// keyed by pid_tgid, until inet_stream_connect returns with the outcome.
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, 10240);
+    __type(key, u64);
+    __type(value, struct event);
+} connects SEC(".maps");
+
+static __always_inline int trace_connect_entry(struct sock *sk) {
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    bpf_map_update_elem(&sockets, &pid_tgid, &sk, BPF_ANY);
+    return 0;
+}
+
+static __always_inline int trace_connect_return(struct pt_regs *ctx, int ret) {
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    struct sock **skpp = bpf_map_lookup_elem(&sockets, &pid_tgid);
+    if (!skpp) {
+        return 0;
+    }
+
+    struct sock *sk = *skpp;
+    bpf_map_delete_elem(&sockets, &pid_tgid);
+
+    struct sock_common skc = {};
+    if (bpf_probe_read(&skc, sizeof(skc), &sk->__sk_common) < 0) {
+        return 0;
+    }
+
+    struct event tcp_info = {};
+    tcp_info.ts_us = bpf_ktime_get_ns() / 1000;
+    tcp_info.pid = pid_tgid >> 32;
+    tcp_info.uid = (u32)bpf_get_current_uid_gid();
+    tcp_info.af = skc.skc_family;
+    tcp_info.dport = bpf_ntohs(skc.skc_dport);
+    tcp_info.sport = skc.skc_num;
+    if (skc.skc_family == AF_INET) {
+        tcp_info.saddr = skc.skc_rcv_saddr;
+        tcp_info.daddr = skc.skc_daddr;
+    } else if (skc.skc_family == AF_INET6) {
+        __builtin_memcpy(tcp_info.saddr6, skc.skc_v6_rcv_saddr.in6_u.u6_addr8, 16);
+        __builtin_memcpy(tcp_info.daddr6, skc.skc_v6_daddr.in6_u.u6_addr8, 16);
+    } else {
+        return 0;
+    }
+    if (is_excluded(tcp_info.af, tcp_info.daddr, tcp_info.daddr6, tcp_info.dport)) {
+        return 0;
+    }
+    bpf_get_current_comm(&tcp_info.comm, TASK_COMM_LEN);
+    tcp_info.ids = current_task_ids();
+
+    if (ret != 0) {
+        // Failed before sending the SYN, inet_stream_connect returns the same error.
+        tcp_info.ret = ret;
+        if (summary_mode()) {
+            summary_add(tcp_info.af, tcp_info.daddr, tcp_info.af == AF_INET6 ? tcp_info.daddr6 : 0, tcp_info.dport,
+                        IPPROTO_TCP, tcp_info.pid, tcp_info.uid, tcp_info.comm, &tcp_info.ids, ret);
+            return 0;
+        }
+        bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &tcp_info, sizeof(tcp_info));
+        return 0;
+    }
+
+    bpf_map_update_elem(&connects, &pid_tgid, &tcp_info, BPF_ANY);
+    return 0;
+}
+
+SEC("kprobe/tcp_v4_connect")
+int BPF_KPROBE(tcp_v4_connect, struct sock *sk) {
+    return trace_connect_entry(sk);
+}
+
+SEC("kretprobe/tcp_v4_connect")
+int BPF_KRETPROBE(tcp_v4_connect_ret, int ret) {
+    return trace_connect_return(ctx, ret);
+}
+
+SEC("kprobe/tcp_v6_connect")
+int BPF_KPROBE(tcp_v6_connect, struct sock *sk) {
+    return trace_connect_entry(sk);
+}
+
+SEC("kretprobe/tcp_v6_connect")
+int BPF_KRETPROBE(tcp_v6_connect_ret, int ret) {
+    return trace_connect_return(ctx, ret);
+}
+
+SEC("kretprobe/inet_stream_connect")
+int BPF_KRETPROBE(inet_stream_connect_ret, int ret) {
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    struct event *tcp_info = bpf_map_lookup_elem(&connects, &pid_tgid);
+    if (!tcp_info) {
+        return 0;
+    }
+
+    tcp_info->ret = ret;
+    if (summary_mode()) {
+        summary_add(tcp_info->af, tcp_info->daddr, tcp_info->af == AF_INET6 ? tcp_info->daddr6 : 0, tcp_info->dport,
+                    IPPROTO_TCP, tcp_info->pid, tcp_info->uid, tcp_info->comm, &tcp_info->ids, ret);
+    } else {
+        bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, tcp_info, sizeof(*tcp_info));
+    }
+    bpf_map_delete_elem(&connects, &pid_tgid);
+    return 0;
+}
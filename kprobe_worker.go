@@ -0,0 +1,98 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"log"
+
+	. "github.com/gotoolkits/lightmon/event"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/features"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 kprobe kprobeTcpConnectSrc.c -- -Iheaders/
+
+func setupBpfKprobeWorkers() {
+	if !Kprobe_Runtime_Verifier("tcp_v4_connect") {
+		log.Fatalln("tcp_v4_connect is not available for kprobes")
+	}
+	if err := checkCompactSockLayout(); err != nil {
+		log.Fatalf("kprobe program does not match this kernel, use -ebpf_type 0 or 1: %v", err)
+	}
+
+	err := features.HaveProgramType(ebpf.Kprobe)
+	if errors.Is(err, ebpf.ErrNotSupported) {
+		log.Fatalln("kprobe program type is not supported")
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	spec, err := loadKprobe()
+	if err != nil {
+		log.Fatalf("loading kprobe spec: %v", err)
+	}
+	setSummarySpec(spec)
+
+	// Load pre-compiled programs and maps into the kernel.
+	objs := kprobeObjects{}
+	if err := spec.LoadAndAssign(&objs, collectionOptions()); err != nil {
+		log.Fatalf("loading kprobe objects: %v", err)
+	}
+	addCloser(&objs)
+	loadKernelExclusions(objs.ExcludedPorts, objs.ExcludedNets)
+
+	probes := []struct {
+		symbol string
+		prog   *ebpf.Program
+		ret    bool
+	}{
+		{"tcp_v4_connect", objs.TcpV4Connect, false},
+		{"tcp_v4_connect", objs.TcpV4ConnectRet, true},
+		{"tcp_v6_connect", objs.TcpV6Connect, false},
+		{"tcp_v6_connect", objs.TcpV6ConnectRet, true},
+		{"inet_stream_connect", objs.InetStreamConnectRet, true},
+	}
+	for _, p := range probes {
+		var kp link.Link
+		if p.ret {
+			kp, err = link.Kretprobe(p.symbol, p.prog, nil)
+		} else {
+			kp, err = link.Kprobe(p.symbol, p.prog, nil)
+		}
+		if err != nil {
+			log.Fatalf("attaching kprobe %s: %s", p.symbol, err)
+		}
+		addCloser(kp)
+	}
+
+	rd := newPerfReader("kprobe", objs.Events)
+	startSummary("kprobe", objs.ConnSummary, objs.SummaryDrops)
+
+	go (func() {
+		for {
+			if !readKprobeEvents(rd) {
+				return
+			}
+		}
+	})()
+}
+
+func readKprobeEvents(rd *perf.Reader) bool {
+	var event TcpEvent
+	sample, ok := readPerfSample(rd, "kprobe")
+	if !ok {
+		return false
+	}
+	if sample == nil || !decodeSample("kprobe", sample, &event) {
+		return true
+	}
+
+	printEvent(newTcpEventPayload(&event))
+	return true
+}
@@ -0,0 +1,152 @@
+package linux
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// CgroupV2Root returns where the cgroup v2 hierarchy is mounted, either
+// CgroupRoot itself or its unified directory on hybrid hosts. It returns ""
+// when there is no cgroup v2 hierarchy.
+func CgroupV2Root() string {
+	for _, dir := range []string{CgroupRoot, filepath.Join(CgroupRoot, "unified")} {
+		if _, err := os.Stat(filepath.Join(dir, "cgroup.controllers")); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+// CgroupV2Path returns the cgroup v2 path of a /proc/<pid>/cgroup file, the
+// "0::" entry.
+func CgroupV2Path(content string) (string, bool) {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// CgroupIDForPid returns the ID of the cgroup v2 a process belongs to, the
+// value bpf_get_current_cgroup_id() returns in its context.
+func CgroupIDForPid(pid string) (uint64, error) {
+	root := CgroupV2Root()
+	if root == "" {
+		return 0, fmt.Errorf("cgroup v2 is not mounted under %s", CgroupRoot)
+	}
+
+	content, err := os.ReadFile(filepath.Join("/proc", pid, "cgroup"))
+	if err != nil {
+		return 0, err
+	}
+	path, ok := CgroupV2Path(string(content))
+	if !ok {
+		return 0, fmt.Errorf("pid %s: no cgroup v2 entry", pid)
+	}
+
+	var st unix.Stat_t
+	if err := unix.Stat(filepath.Join(root, path), &st); err != nil {
+		return 0, fmt.Errorf("pid %s: %w", pid, err)
+	}
+	return st.Ino, nil
+}
+
+// ContainerIDFromCgroup returns the ID of the container a /proc/<pid>/cgroup
+// file belongs to, or "" for processes outside containers. It understands
+// cgroup v1 and v2 paths of the cgroupfs driver, e.g.
+//
+//	/docker/<id>
+//	/kubepods/burstable/pod<uid>/<id>
+//
+// and of the systemd driver, e.g.
+//
+//	/system.slice/docker-<id>.scope
+//	/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod<uid>.slice/cri-containerd-<id>.scope
+//	/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-<id>.scope/container
+//
+// With nested containers the innermost one is returned.
+func ContainerIDFromCgroup(content string) string {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if id := containerIDFromPath(parts[2]); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+func containerIDFromPath(path string) string {
+	dirs := strings.Split(path, "/")
+	for i := len(dirs) - 1; i >= 0; i-- {
+		name := strings.TrimSuffix(dirs[i], ".scope")
+		// crio-conmon-<id> and libpod-conmon-<id> hold the container
+		// monitor, which runs outside the container.
+		if strings.Contains(name, "-conmon-") {
+			return ""
+		}
+		if j := strings.LastIndexByte(name, '-'); j >= 0 {
+			name = name[j+1:]
+		}
+		if isContainerID(name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// isContainerID reports whether s looks like a container ID, 64 hex digits.
+func isContainerID(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainerIDForPid returns the ID of the container a process runs in, or ""
+// if it runs on the host, see ContainerIDFromCgroup.
+func ContainerIDForPid(pid string) (string, error) {
+	content, err := os.ReadFile(filepath.Join("/proc", pid, "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	return ContainerIDFromCgroup(string(content)), nil
+}
+
+// NamespaceInodeForPid returns the inode of a namespace ("net", "pid", ...)
+// of a process, or 0 if the process is gone.
+func NamespaceInodeForPid(pid int, ns string) uint32 {
+	// The link reads like "net:[4026531840]".
+	target, err := os.Readlink("/proc/" + strconv.Itoa(pid) + "/ns/" + ns)
+	if err != nil {
+		return 0
+	}
+	start := strings.IndexByte(target, '[')
+	end := strings.LastIndexByte(target, ']')
+	if start < 0 || end <= start {
+		return 0
+	}
+	inode, err := strconv.ParseUint(target[start+1:end], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(inode)
+}
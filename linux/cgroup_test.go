@@ -0,0 +1,166 @@
+package linux
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCgroupV2Path(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantOk  bool
+	}{
+		{
+			name:    "unified",
+			content: "0::/system.slice/docker-0123abcd.scope\n",
+			want:    "/system.slice/docker-0123abcd.scope",
+			wantOk:  true,
+		},
+		{
+			name:    "hybrid",
+			content: "12:memory:/docker/0123abcd\n1:name=systemd:/docker/0123abcd\n0::/docker/0123abcd\n",
+			want:    "/docker/0123abcd",
+			wantOk:  true,
+		},
+		{
+			name:    "v1 only",
+			content: "12:memory:/docker/0123abcd\n1:name=systemd:/docker/0123abcd\n",
+			wantOk:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := CgroupV2Path(tt.content)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("CgroupV2Path() = %q, %v; want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestContainerIDFromCgroup(t *testing.T) {
+	const id = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	const id2 = "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "docker cgroupfs v1",
+			content: "12:memory:/docker/" + id + "\n11:cpu,cpuacct:/docker/" + id + "\n1:name=systemd:/docker/" + id + "\n",
+			want:    id,
+		},
+		{
+			name:    "docker cgroupfs v2",
+			content: "0::/docker/" + id + "\n",
+			want:    id,
+		},
+		{
+			name:    "docker systemd v2",
+			content: "0::/system.slice/docker-" + id + ".scope\n",
+			want:    id,
+		},
+		{
+			name:    "kubernetes cgroupfs v1",
+			content: "4:memory:/kubepods/burstable/pod8d7b2c1e-4f4e-4b8a-9c39-0e6f0b3a1d2c/" + id + "\n",
+			want:    id,
+		},
+		{
+			name:    "kubernetes systemd containerd v2",
+			content: "0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod8d7b2c1e_4f4e_4b8a_9c39_0e6f0b3a1d2c.slice/cri-containerd-" + id + ".scope\n",
+			want:    id,
+		},
+		{
+			name:    "kubernetes systemd cri-o v1",
+			content: "10:pids:/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod8d7b2c1e_4f4e.slice/crio-" + id + ".scope\n",
+			want:    id,
+		},
+		{
+			name:    "cri-o conmon",
+			content: "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod8d7b2c1e_4f4e.slice/crio-conmon-" + id + ".scope\n",
+			want:    "",
+		},
+		{
+			name:    "rootless podman",
+			content: "0::/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + id + ".scope/container\n",
+			want:    id,
+		},
+		{
+			name:    "nested",
+			content: "0::/docker/" + id + "/docker/" + id2 + "\n",
+			want:    id2,
+		},
+		{
+			name:    "host",
+			content: "0::/user.slice/user-1000.slice/session-3.scope\n",
+			want:    "",
+		},
+		{
+			name:    "hybrid host",
+			content: "12:memory:/system.slice/sshd.service\n1:name=systemd:/system.slice/sshd.service\n0::/system.slice/sshd.service\n",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContainerIDFromCgroup(tt.content); got != tt.want {
+				t.Errorf("ContainerIDFromCgroup() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerIDForPidForNotExistingPid(t *testing.T) {
+	if _, err := ContainerIDForPid("32769"); err == nil { // There should be no such PID, default MAX PID is 32768
+		t.Error("ContainerIDForPid(32769) succeeded")
+	}
+}
+
+func TestCgroupV2Root(t *testing.T) {
+	root := t.TempDir()
+	saved := CgroupRoot
+	CgroupRoot = root
+	defer func() { CgroupRoot = saved }()
+
+	if got := CgroupV2Root(); got != "" {
+		t.Errorf("CgroupV2Root() = %q without cgroup.controllers; want \"\"", got)
+	}
+
+	if err := os.Mkdir(root+"/unified", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(root+"/unified/cgroup.controllers", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := CgroupV2Root(); got != root+"/unified" {
+		t.Errorf("CgroupV2Root() = %q; want %q", got, root+"/unified")
+	}
+
+	if err := os.WriteFile(root+"/cgroup.controllers", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := CgroupV2Root(); got != root {
+		t.Errorf("CgroupV2Root() = %q; want %q", got, root)
+	}
+}
+
+func TestNamespaceInodeForPidForNotExistingPid(t *testing.T) {
+	if got := NamespaceInodeForPid(32769, "net"); got != 0 { // There should be no such PID, default MAX PID is 32768
+		t.Errorf("NamespaceInodeForPid(32769) = %d; want 0", got)
+	}
+}
+
+func TestNamespaceInodeForPid(t *testing.T) {
+	if _, err := os.Readlink("/proc/self/ns/net"); err != nil {
+		t.Skipf("no namespace links: %v", err)
+	}
+	if got := NamespaceInodeForPid(os.Getpid(), "net"); got == 0 {
+		t.Error("NamespaceInodeForPid(self, net) = 0")
+	}
+}
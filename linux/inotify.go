@@ -0,0 +1,125 @@
+package linux
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// dirWatchMask reports entries being created, written, renamed or removed.
+const dirWatchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_CLOSE_WRITE |
+	unix.IN_MOVED_TO | unix.IN_MOVED_FROM | unix.IN_ONLYDIR
+
+// DirWatcher watches directories with inotify, each together with its
+// subdirectories down to a depth. Subdirectories created later are watched
+// as they appear, removed ones are dropped by the kernel.
+type DirWatcher struct {
+	file *os.File
+
+	mu   sync.Mutex
+	dirs map[int32]watchedDir
+}
+
+type watchedDir struct {
+	path  string
+	depth int
+}
+
+// NewDirWatcher returns a DirWatcher without any directories.
+func NewDirWatcher() (*DirWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	return &DirWatcher{
+		// Non-blocking, so reads go through the runtime poller and Close
+		// interrupts Wait.
+		file: os.NewFile(uintptr(fd), "inotify"),
+		dirs: make(map[int32]watchedDir),
+	}, nil
+}
+
+// Add watches dir and its subdirectories depth levels down. Adding a
+// directory again is cheap, it keeps the existing watch.
+func (w *DirWatcher) Add(dir string, depth int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.add(dir, depth)
+}
+
+func (w *DirWatcher) add(dir string, depth int) error {
+	conn, err := w.file.SyscallConn()
+	if err != nil {
+		return err
+	}
+	// Not through Fd, which may put the file into blocking mode, see
+	// os.File.Fd.
+	var wd int
+	if cerr := conn.Control(func(fd uintptr) {
+		wd, err = unix.InotifyAddWatch(int(fd), dir, dirWatchMask)
+	}); cerr != nil {
+		return cerr
+	}
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+	if cur, ok := w.dirs[int32(wd)]; !ok || cur.depth < depth {
+		w.dirs[int32(wd)] = watchedDir{dir, depth}
+	}
+	if depth == 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			// Subdirectories may be gone already.
+			w.add(filepath.Join(dir, entry.Name()), depth-1)
+		}
+	}
+	return nil
+}
+
+// Wait blocks until something changed in the watched directories, and
+// watches the subdirectories that were created meanwhile. An event queue
+// overflow counts as a change too.
+func (w *DirWatcher) Wait() error {
+	var buf [16 * (unix.SizeofInotifyEvent + unix.NAME_MAX + 1)]byte
+	n, err := w.file.Read(buf[:])
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for off := 0; off+unix.SizeofInotifyEvent <= n; {
+		event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+		nameStart := off + unix.SizeofInotifyEvent
+		name := string(bytes.TrimRight(buf[nameStart:nameStart+int(event.Len)], "\x00"))
+		off = nameStart + int(event.Len)
+
+		if event.Mask&unix.IN_IGNORED != 0 {
+			delete(w.dirs, event.Wd)
+			continue
+		}
+		if event.Mask&unix.IN_ISDIR == 0 || event.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) == 0 {
+			continue
+		}
+		if dir, ok := w.dirs[event.Wd]; ok && dir.depth > 0 {
+			w.add(filepath.Join(dir.path, name), dir.depth-1)
+		}
+	}
+	return nil
+}
+
+// Close stops watching, a pending Wait returns an error.
+func (w *DirWatcher) Close() error {
+	return w.file.Close()
+}
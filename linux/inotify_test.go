@@ -0,0 +1,102 @@
+package linux
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitChange reports whether w sees a change within timeout.
+func waitChange(w *DirWatcher, timeout time.Duration) bool {
+	done := make(chan error, 1)
+	go func() { done <- w.Wait() }()
+	select {
+	case err := <-done:
+		return err == nil
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestDirWatcher(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewDirWatcher()
+	if err != nil {
+		t.Skipf("inotify not available: %v", err)
+	}
+	defer w.Close()
+	if err := w.Add(root, 1); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := w.Add(filepath.Join(root, "missing"), 1); err == nil {
+		t.Error("Add() of a missing directory should fail")
+	}
+
+	steps := []struct {
+		name string
+		do   func() error
+	}{
+		{"existing subdirectory", func() error {
+			return os.WriteFile(filepath.Join(root, "old", "pidfile"), []byte("42"), 0644)
+		}},
+		{"new subdirectory", func() error { return os.Mkdir(filepath.Join(root, "new"), 0755) }},
+		{"file in new subdirectory", func() error {
+			return os.WriteFile(filepath.Join(root, "new", "init.pid"), []byte("42"), 0644)
+		}},
+		{"removal", func() error { return os.RemoveAll(filepath.Join(root, "old")) }},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatal(err)
+		}
+		if !waitChange(w, time.Second) {
+			t.Fatalf("%s: Wait() saw no change", step.name)
+		}
+	}
+
+	// Below the depth, nothing is watched.
+	if err := os.Mkdir(filepath.Join(root, "new", "rootfs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if !waitChange(w, time.Second) {
+		t.Fatal("Wait() saw no change for the depth 1 directory")
+	}
+	if err := os.WriteFile(filepath.Join(root, "new", "rootfs", "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if waitChange(w, 200*time.Millisecond) {
+		t.Error("Wait() saw a change below the depth")
+	}
+}
+
+func TestDirWatcherCloseInterruptsWait(t *testing.T) {
+	w, err := NewDirWatcher()
+	if err != nil {
+		t.Skipf("inotify not available: %v", err)
+	}
+	if err := w.Add(t.TempDir(), 1); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- w.Wait() }()
+	// Let Wait block in the read.
+	time.Sleep(50 * time.Millisecond)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Wait() after Close() should fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close() did not interrupt Wait()")
+	}
+}
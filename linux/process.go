@@ -2,6 +2,7 @@ package linux
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
@@ -58,3 +59,50 @@ func ProcessArgsForPid(pid int) string {
 
 	return strings.Join(parts[1:], " ")
 }
+
+// ClockTicks is USER_HZ, the unit of the times in /proc/<pid>/stat.
+const ClockTicks = 100
+
+// ProcessStat holds the fields of /proc/<pid>/stat lightmon uses.
+type ProcessStat struct {
+	PPid       int
+	StartTicks uint64 // start time in ClockTicks since boot
+}
+
+// ProcessStatForPid reads /proc/<pid>/stat.
+func ProcessStatForPid(pid int) (ProcessStat, error) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return ProcessStat{}, err
+	}
+	return parseProcessStat(string(data))
+}
+
+func parseProcessStat(content string) (ProcessStat, error) {
+	// comm is in parentheses and may contain spaces and parentheses itself.
+	end := strings.LastIndexByte(content, ')')
+	if end < 0 {
+		return ProcessStat{}, fmt.Errorf("malformed stat: %q", content)
+	}
+	// Fields after comm, starting with state (field 3).
+	fields := strings.Fields(content[end+1:])
+	if len(fields) < 20 {
+		return ProcessStat{}, fmt.Errorf("malformed stat: %q", content)
+	}
+
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return ProcessStat{}, fmt.Errorf("malformed ppid: %w", err)
+	}
+	start, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return ProcessStat{}, fmt.Errorf("malformed starttime: %w", err)
+	}
+	return ProcessStat{PPid: ppid, StartTicks: start}, nil
+}
+
+// StartNsToTicks converts a start time in ns since boot, as recorded by the
+// eBPF programs, to the ClockTicks of ProcessStat.StartTicks.
+func StartNsToTicks(ns uint64) uint64 {
+	return ns / (1000000000 / ClockTicks)
+}
@@ -1,6 +1,9 @@
 package linux
 
-import "testing"
+import (
+	"os"
+	"testing"
+)
 
 func TestProcessPathForPidForNotExistingPid(t *testing.T) {
 	got := ProcessPathForPid(32769) // There should be no such PID, default MAX PID is 32768
@@ -17,3 +20,36 @@ func TestProcessArgsForPidForNotExistingPid(t *testing.T) {
 		t.Errorf("ProcessArgsForPid(32769) = %s; want %s", got, want)
 	}
 }
+
+func TestParseProcessStat(t *testing.T) {
+	// comm "my (proc) x" contains spaces and parentheses.
+	content := "4242 (my (proc) x) S 4200 4242 4200 0 -1 4194560 1187 0 0 0 3 1 0 0 20 0 1 0 987654 7061504 900 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0\n"
+	got, err := parseProcessStat(content)
+	if err != nil {
+		t.Fatalf("parseProcessStat: %v", err)
+	}
+	want := ProcessStat{PPid: 4200, StartTicks: 987654}
+	if got != want {
+		t.Errorf("parseProcessStat = %+v; want %+v", got, want)
+	}
+
+	if _, err := parseProcessStat("4242 (short) S 1 2"); err == nil {
+		t.Error("parseProcessStat should fail on a short stat")
+	}
+}
+
+func TestProcessStatForPid(t *testing.T) {
+	stat, err := ProcessStatForPid(os.Getpid())
+	if err != nil {
+		t.Skipf("no /proc: %v", err)
+	}
+	if stat.PPid != os.Getppid() {
+		t.Errorf("ProcessStatForPid(self).PPid = %d; want %d", stat.PPid, os.Getppid())
+	}
+}
+
+func TestStartNsToTicks(t *testing.T) {
+	if got := StartNsToTicks(12345678901); got != 1234 {
+		t.Errorf("StartNsToTicks(12345678901) = %d; want 1234", got)
+	}
+}
@@ -0,0 +1,115 @@
+package linux
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultMaxProcesses bounds the table when NewProcessTable is given no limit.
+const defaultMaxProcesses = 65536
+
+type processEntry struct {
+	path     string
+	args     string
+	exitedAt time.Time // zero while the process is running
+}
+
+type exitedProcess struct {
+	pid   int
+	entry *processEntry
+}
+
+// ProcessTable remembers the executable and arguments of processes from
+// the moment they exec, so events of short-lived processes can be enriched
+// after the process is gone from /proc. Entries are kept for a grace period
+// after exit, as events of the process may be read after its exit.
+type ProcessTable struct {
+	mu           sync.Mutex
+	entries      map[int]*processEntry
+	exited       []exitedProcess // in the order of exit, so of expiry
+	grace        time.Duration
+	maxProcesses int
+	now          func() time.Time
+}
+
+// NewProcessTable creates a ProcessTable that keeps exited processes for
+// grace and holds at most maxProcesses processes.
+func NewProcessTable(grace time.Duration, maxProcesses int) *ProcessTable {
+	if maxProcesses <= 0 {
+		maxProcesses = defaultMaxProcesses
+	}
+	return &ProcessTable{
+		entries:      make(map[int]*processEntry),
+		grace:        grace,
+		maxProcesses: maxProcesses,
+		now:          time.Now,
+	}
+}
+
+// Exec records that pid executed path with args, replacing what was known
+// about pid.
+func (t *ProcessTable) Exec(pid int, path string, args string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.entries[pid]; !ok && len(t.entries) >= t.maxProcesses {
+		t.purge(t.now())
+		if len(t.entries) >= t.maxProcesses {
+			return
+		}
+	}
+	t.entries[pid] = &processEntry{path: path, args: args}
+}
+
+// Exit records that pid exited, it is evicted after the grace period.
+func (t *ProcessTable) Exit(pid int) {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.entries[pid]; ok && e.exitedAt.IsZero() {
+		e.exitedAt = now
+		t.exited = append(t.exited, exitedProcess{pid, e})
+	}
+	t.purge(now)
+}
+
+// Lookup returns the executable and arguments of pid.
+func (t *ProcessTable) Lookup(pid int) (path string, args string, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.entries[pid]
+	if !ok {
+		return "", "", false
+	}
+	if t.expired(e, t.now()) {
+		delete(t.entries, pid)
+		return "", "", false
+	}
+	return e.path, e.args, true
+}
+
+// Len returns the number of processes in the table, including exited ones
+// that have not been purged yet.
+func (t *ProcessTable) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.entries)
+}
+
+func (t *ProcessTable) expired(e *processEntry, now time.Time) bool {
+	return !e.exitedAt.IsZero() && now.Sub(e.exitedAt) >= t.grace
+}
+
+// purge evicts the processes whose grace period is over, the oldest exits
+// first, so it only touches the expired ones.
+func (t *ProcessTable) purge(now time.Time) {
+	n := 0
+	for ; n < len(t.exited) && t.expired(t.exited[n].entry, now); n++ {
+		// The PID may have been exec'd again or looked up since.
+		if p := t.exited[n]; t.entries[p.pid] == p.entry {
+			delete(t.entries, p.pid)
+		}
+		t.exited[n] = exitedProcess{}
+	}
+	t.exited = t.exited[n:]
+}
@@ -0,0 +1,86 @@
+package linux
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestProcessTable(maxProcesses int) (*ProcessTable, *time.Time) {
+	now := time.Date(2025, 4, 17, 14, 0, 0, 0, time.UTC)
+	pt := NewProcessTable(10*time.Second, maxProcesses)
+	pt.now = func() time.Time { return now }
+	return pt, &now
+}
+
+func TestProcessTable_Lookup(t *testing.T) {
+	pt, now := newTestProcessTable(0)
+	pt.Exec(4242, "/usr/bin/curl", "-s example.com")
+
+	path, args, ok := pt.Lookup(4242)
+	if !ok || path != "/usr/bin/curl" || args != "-s example.com" {
+		t.Errorf("Lookup() = %q, %q, %v; want the exec'd process", path, args, ok)
+	}
+	if _, _, ok := pt.Lookup(4243); ok {
+		t.Error("Lookup() of unknown pid should fail")
+	}
+
+	pt.Exit(4242)
+	*now = now.Add(5 * time.Second)
+	if _, _, ok := pt.Lookup(4242); !ok {
+		t.Error("Lookup() within the grace period after exit should succeed")
+	}
+
+	*now = now.Add(5 * time.Second)
+	if _, _, ok := pt.Lookup(4242); ok {
+		t.Error("Lookup() after the grace period should fail")
+	}
+	if pt.Len() != 0 {
+		t.Errorf("Len() = %d, want exited process removed", pt.Len())
+	}
+}
+
+func TestProcessTable_ExecReplaces(t *testing.T) {
+	pt, _ := newTestProcessTable(0)
+	pt.Exec(4242, "/bin/sh", "-c curl example.com")
+	pt.Exec(4242, "/usr/bin/curl", "example.com")
+
+	if path, _, _ := pt.Lookup(4242); path != "/usr/bin/curl" {
+		t.Errorf("Lookup() = %q, want the last exec", path)
+	}
+}
+
+func TestProcessTable_MaxProcesses(t *testing.T) {
+	pt, now := newTestProcessTable(2)
+	pt.Exec(1, "/a", "")
+	pt.Exec(2, "/b", "")
+	pt.Exec(3, "/c", "")
+	if _, _, ok := pt.Lookup(3); ok {
+		t.Error("Exec() beyond maxProcesses should be dropped")
+	}
+
+	pt.Exit(1)
+	*now = now.Add(10 * time.Second)
+	pt.Exec(3, "/c", "")
+	if _, _, ok := pt.Lookup(3); !ok {
+		t.Error("Exec() should purge exited processes to make room")
+	}
+}
+
+func TestProcessTable_ExecAfterExit(t *testing.T) {
+	pt, now := newTestProcessTable(0)
+	pt.Exec(4242, "/usr/bin/curl", "")
+	pt.Exit(4242)
+	// The PID is reused before the grace period is over.
+	pt.Exec(4242, "/usr/bin/wget", "")
+	pt.Exec(4243, "/bin/sh", "")
+	pt.Exit(4243)
+
+	*now = now.Add(10 * time.Second)
+	pt.Exit(1)
+	if path, _, ok := pt.Lookup(4242); !ok || path != "/usr/bin/wget" {
+		t.Errorf("Lookup() = %q, %v; want the running process kept", path, ok)
+	}
+	if pt.Len() != 1 {
+		t.Errorf("Len() = %d, want exited process removed", pt.Len())
+	}
+}
@@ -0,0 +1,71 @@
+package linux
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// CgroupRoot is where the cgroup v2 hierarchy is mounted.
+var CgroupRoot = "/sys/fs/cgroup"
+
+// CgroupIDs resolves a comma separated list of cgroup v2 IDs and cgroup
+// paths into IDs. Paths not under the cgroup v2 mount are taken from it, so
+// both the form in /proc/<pid>/cgroup and relative paths work. The ID of a
+// cgroup v2 directory is its inode number.
+func CgroupIDs(list string) ([]uint64, error) {
+	root := CgroupV2Root()
+	if root == "" {
+		root = CgroupRoot
+	}
+
+	var ids []uint64
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if id, err := strconv.ParseUint(item, 10, 64); err == nil {
+			ids = append(ids, id)
+			continue
+		}
+
+		path := item
+		if !isUnder(path, root) {
+			path = filepath.Join(root, path)
+		}
+		var st unix.Stat_t
+		if err := unix.Stat(path, &st); err != nil {
+			return nil, fmt.Errorf("cgroup %s: %w", item, err)
+		}
+		ids = append(ids, st.Ino)
+	}
+	return ids, nil
+}
+
+// isUnder reports whether path is dir or below it.
+func isUnder(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsAbs(path) && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// PidNamespace returns the device and inode of a PID namespace, given either
+// a PID whose namespace is used or a path like /proc/<pid>/ns/pid. The device
+// is encoded the way the kernel stores dev_t, as bpf_get_ns_current_pid_tgid
+// expects it.
+func PidNamespace(pidOrPath string) (dev, ino uint64, err error) {
+	path := pidOrPath
+	if _, err := strconv.Atoi(pidOrPath); err == nil {
+		path = "/proc/" + pidOrPath + "/ns/pid"
+	}
+
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return 0, 0, fmt.Errorf("pid namespace %s: %w", pidOrPath, err)
+	}
+	dev = uint64(unix.Major(uint64(st.Dev)))<<20 | uint64(unix.Minor(uint64(st.Dev)))
+	return dev, st.Ino, nil
+}
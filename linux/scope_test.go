@@ -0,0 +1,109 @@
+package linux
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCgroupIDs(t *testing.T) {
+	root := t.TempDir()
+	dir := root + "/system.slice"
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	var st unix.Stat_t
+	if err := unix.Stat(dir, &st); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := CgroupRoot
+	CgroupRoot = root
+	defer func() { CgroupRoot = saved }()
+
+	got, err := CgroupIDs("12, 34," + dir)
+	if err != nil {
+		t.Fatalf("CgroupIDs: %v", err)
+	}
+	want := []uint64{12, 34, st.Ino}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CgroupIDs = %v; want %v", got, want)
+	}
+}
+
+func TestCgroupIDsRelativeToRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(root+"/system.slice", 0755); err != nil {
+		t.Fatal(err)
+	}
+	var st unix.Stat_t
+	if err := unix.Stat(root+"/system.slice", &st); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := CgroupRoot
+	CgroupRoot = root
+	defer func() { CgroupRoot = saved }()
+
+	got, err := CgroupIDs("system.slice")
+	if err != nil {
+		t.Fatalf("CgroupIDs: %v", err)
+	}
+	if len(got) != 1 || got[0] != st.Ino {
+		t.Errorf("CgroupIDs = %v; want [%d]", got, st.Ino)
+	}
+
+	if _, err := CgroupIDs("missing.slice"); err == nil {
+		t.Error("CgroupIDs(missing.slice) should fail")
+	}
+}
+
+func TestCgroupIDsProcForm(t *testing.T) {
+	root := t.TempDir()
+	dir := root + "/kubepods.slice/kubepods-besteffort.slice"
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	var st unix.Stat_t
+	if err := unix.Stat(dir, &st); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := CgroupRoot
+	CgroupRoot = root
+	defer func() { CgroupRoot = saved }()
+
+	// As in /proc/<pid>/cgroup and systemd.
+	got, err := CgroupIDs("/kubepods.slice/kubepods-besteffort.slice")
+	if err != nil {
+		t.Fatalf("CgroupIDs: %v", err)
+	}
+	if len(got) != 1 || got[0] != st.Ino {
+		t.Errorf("CgroupIDs = %v; want [%d]", got, st.Ino)
+	}
+}
+
+func TestPidNamespace(t *testing.T) {
+	path := "/proc/self/ns/pid"
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		t.Skipf("no pid namespace file: %v", err)
+	}
+
+	for _, arg := range []string{path, strconv.Itoa(os.Getpid())} {
+		_, ino, err := PidNamespace(arg)
+		if err != nil {
+			t.Fatalf("PidNamespace(%s): %v", arg, err)
+		}
+		if ino != st.Ino {
+			t.Errorf("PidNamespace(%s) inode = %d; want %d", arg, ino, st.Ino)
+		}
+	}
+
+	if _, _, err := PidNamespace("32769"); err == nil {
+		t.Error("PidNamespace(32769) should fail") // no such PID, default MAX PID is 32768
+	}
+}
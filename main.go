@@ -4,11 +4,9 @@
 package main
 
 import (
-	"bytes"
-	"encoding/binary"
-	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
@@ -18,59 +16,175 @@ import (
 	"time"
 
 	"github.com/gotoolkits/lightmon/conv"
+	"github.com/gotoolkits/lightmon/dns"
 	"github.com/gotoolkits/lightmon/dockerinfo"
 	. "github.com/gotoolkits/lightmon/event"
 	"github.com/gotoolkits/lightmon/linux"
 	. "github.com/gotoolkits/lightmon/outputer"
 
-	"github.com/cilium/ebpf"
-	"github.com/cilium/ebpf/features"
-	"github.com/cilium/ebpf/link"
-	"github.com/cilium/ebpf/perf"
-	"github.com/cilium/ebpf/ringbuf"
 	"github.com/cilium/ebpf/rlimit"
 	"golang.org/x/sys/unix"
 	"gopkg.in/yaml.v3"
 )
 
-//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 bpf fentryTcpConnectSrc.c -- -Iheaders/
 
 type Config struct {
-	IPv6            bool   `yaml:"ipv6"`
-	K8s             bool   `yaml:"k8s"`
-	Format          string `yaml:"format"`
-	DockerRuntime   string `yaml:"docker_runtime"`
-	DockerData      string `yaml:"docker_data"`
-	ExcludeFilter   string `yaml:"exclude"`
-	LogPath         string `yaml:"logPath"`
-	EbpfType  		int    `yaml:"ebpfType"`
+	IPv6             bool   `yaml:"ipv6"`
+	K8s              bool   `yaml:"k8s"`
+	Format           string `yaml:"format"`
+	DockerRuntime    string `yaml:"docker_runtime"`
+	DockerData       string `yaml:"docker_data"`
+	ContainerRuntime string `yaml:"containerRuntime"`
+	ContainerdState  string `yaml:"containerdState"`
+	CrioRun          string `yaml:"crioRun"`
+	StorageRoot      string `yaml:"storageRoot"`
+	StorageRunRoot   string `yaml:"storageRunRoot"`
+	ExcludeFilter    string `yaml:"exclude"`
+	LogPath          string `yaml:"logPath"`
+	EbpfType         int    `yaml:"ebpfType"`
+	TcpState         bool   `yaml:"tcpState"`
+	Inbound          bool   `yaml:"inbound"`
+	Udp              bool   `yaml:"udp"`
+	UdpDedupWindow   int    `yaml:"udpDedupWindow"`
+	Unix             bool   `yaml:"unix"`
+	Dns              bool   `yaml:"dns"`
+	DnsCache         bool   `yaml:"dnsCache"`
+	Flow             bool   `yaml:"flow"`
+	TcpRetrans       bool   `yaml:"tcpRetrans"`
+	TcpRetransWindow int    `yaml:"tcpRetransWindow"`
+	SummaryInterval  int    `yaml:"summaryInterval"`
+	ProcTable        bool   `yaml:"procTable"`
+	ScopeCgroup      string `yaml:"scopeCgroup"`
+	ScopePidns       string `yaml:"scopePidns"`
+	ScopeComm        string `yaml:"scopeComm"`
+	BtfFile          string `yaml:"btfFile"`
+
+	RingBufferSize     int    `yaml:"ringBufferSize"`
+	PerfBufferPages    int    `yaml:"perfBufferPages"`
+	BufferWatermark    int    `yaml:"bufferWatermark"`
+	LostReportInterval int    `yaml:"lostReportInterval"`
+	LagPolicy          string `yaml:"lagPolicy"`
 }
 
 var (
 	outputer IOutputer
 	config Config
-	ebpfType int
+	ebpfType EBPF_PROG_TYPE
+	hostCache *dns.Cache
+	procTable *linux.ProcessTable
+	containerRuntime dockerinfo.Runtime
 )
 
 type EBPF_PROG_TYPE int 
 const (
 	FENTRY EBPF_PROG_TYPE = iota
 	TRACEPOINT
+	KPROBE
+	AUTO
 )
 
+func (t EBPF_PROG_TYPE) String() string {
+	switch t {
+	case FENTRY:
+		return "fentry"
+	case TRACEPOINT:
+		return "tracepoint"
+	case KPROBE:
+		return "kprobe"
+	case AUTO:
+		return "auto"
+	}
+	return strconv.Itoa(int(t))
+}
+
 func main() {
 	initConfigs()
     
-	// First load docker info to cache
-	dockerinfo.LoadContainerInfosToCache(config.DockerRuntime, config.DockerData)
-	// Cycle to load docker info to cache
-	go runForLocalDockerInfos()
+	if containerRuntime != nil {
+		// First load container info to cache
+		dockerinfo.LoadContainerInfosToCache(containerRuntime)
+		// Cycle to load docker info to cache
+		go runForLocalDockerInfos()
+	}
+
+	// Allow the current process to lock memory for eBPF resources.
+	if err := rlimit.RemoveMemlock(); err != nil {
+		log.Fatal(err)
+	}
+
+	outputer.PrintHeader()
 
-	if ebpfType == int(TRACEPOINT) {
+	// Start before the connect programs, so their events find the processes.
+	if config.ProcTable {
+		setupBpfProcWorkers()
+	}
+
+	switch ebpfType {
+	case TRACEPOINT:
 		setupBpfTPWorkers()
-	} else {
+	case KPROBE:
+		setupBpfKprobeWorkers()
+	default:
 		setupBpfFentryWorkers()
 	}
+
+	if config.TcpState {
+		setupBpfTcpStateWorkers()
+	}
+
+	if config.Inbound {
+		setupBpfAcceptWorkers()
+	}
+
+	if config.Udp {
+		setupBpfUdpWorkers()
+	}
+
+	if config.Unix {
+		setupBpfUnixWorkers()
+	}
+
+	if config.Dns || config.DnsCache {
+		setupBpfDnsWorkers()
+	}
+
+	if config.Flow {
+		setupBpfFlowWorkers()
+	}
+
+	if config.TcpRetrans {
+		setupBpfTcpRetransWorkers()
+	}
+
+	go watchBuffers()
+
+	waitForSignal()
+}
+
+// procExitGrace is how long procTable keeps a process after it exited, for
+// events that are read after the exit event.
+const procExitGrace = 10 * time.Second
+
+// closers holds the eBPF objects, links and readers to release on exit.
+var closers []io.Closer
+
+func addCloser(c io.Closer) {
+	closers = append(closers, c)
+}
+
+// waitForSignal blocks until SIGINT/SIGTERM, then closes everything added
+// with addCloser in reverse order so readers stop before their maps go away.
+func waitForSignal() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	log.Println("Received signal, exiting program..")
+
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil {
+			log.Printf("closing: %s", err)
+		}
+	}
 }
 
 func loadConfig(configPath string) error {
@@ -85,13 +199,39 @@ func initConfigs() {
 	// parse console args
 	var configPath string
 	flag.BoolVar(&config.IPv6, "v6", false, "print ipv6")
-	flag.BoolVar(&config.K8s, "k8s", false, "k8s mode or docker mode")
+	flag.BoolVar(&config.K8s, "k8s", false, "deprecated and ignored, containers are resolved from /proc/<pid>/cgroup")
 	flag.StringVar(&config.Format, "f", "logfile", "table、json or logfile output format")
 	flag.StringVar(&config.LogPath, "log_path", "/data/lightMon-ebpf/logs", "specify logfile output path")
 	flag.StringVar(&config.DockerRuntime, "docker_runtime", "/run/docker", "docker runtime dir path")
 	flag.StringVar(&config.DockerData, "docker_data", "/data/docker", "docker data dir path")
+	flag.StringVar(&config.ContainerRuntime, "runtime", runtimeAuto, "auto | none | docker | containerd | crio | podman, the container runtime to read container names from")
+	flag.StringVar(&config.ContainerdState, "containerd_state", "/run/containerd/io.containerd.runtime.v2.task", "containerd task state dir path")
+	flag.StringVar(&config.CrioRun, "crio_run", "/var/run/crio", "cri-o runtime dir path")
+	flag.StringVar(&config.StorageRoot, "storage_root", "/var/lib/containers/storage", "containers/storage graph root of cri-o and rootful podman")
+	flag.StringVar(&config.StorageRunRoot, "storage_run_root", "/run/containers/storage", "containers/storage run root of cri-o and rootful podman")
 	flag.StringVar(&config.ExcludeFilter, "exclude", "", "exclude output filter")
-	flag.IntVar(&config.EbpfType,"ebpf_type",0," 0(FENTRY) | 1(TRACEPOINT) ")
+	flag.IntVar(&config.EbpfType,"ebpf_type",int(AUTO)," 0(FENTRY) | 1(TRACEPOINT) | 2(KPROBE) | 3(AUTO) ")
+	flag.BoolVar(&config.TcpState, "tcp_state", false, "report TCP state transitions from sock:inet_sock_set_state")
+	flag.BoolVar(&config.Inbound, "inbound", false, "report inbound connections accepted by local processes")
+	flag.BoolVar(&config.Udp, "udp", false, "report UDP sends from udp_sendmsg/udpv6_sendmsg")
+	flag.IntVar(&config.UdpDedupWindow, "udp_dedup_window", 10, "seconds to suppress repeated UDP sends on the same pid and 5-tuple")
+	flag.BoolVar(&config.Unix, "unix", false, "report connects to unix domain sockets with the socket path")
+	flag.BoolVar(&config.Dns, "dns", false, "report DNS queries sent to port 53")
+	flag.BoolVar(&config.DnsCache, "dns_cache", false, "fill host from DNS responses seen by local processes")
+	flag.BoolVar(&config.Flow, "flow", false, "report byte and packet counters of each TCP connection when it closes")
+	flag.BoolVar(&config.TcpRetrans, "tcp_retrans", false, "report TCP retransmissions and resets")
+	flag.IntVar(&config.TcpRetransWindow, "tcp_retrans_window", 1, "seconds to suppress repeated retransmissions or resets on the same tuple")
+	flag.IntVar(&config.SummaryInterval, "summary", 0, "count connects in the kernel and report them per process and destination every N seconds, 0 reports every connect")
+	flag.BoolVar(&config.ProcTable, "proc_table", false, "record path and args of processes at exec for processes gone before their events are read")
+	flag.StringVar(&config.ScopeCgroup, "scope_cgroup", "", "only trace connects from these cgroup v2 paths or IDs, comma separated")
+	flag.StringVar(&config.ScopePidns, "scope_pidns", "", "only trace connects from the PID namespace of this pid or /proc/<pid>/ns/pid path")
+	flag.StringVar(&config.ScopeComm, "scope_comm", "", "only trace connects from these process names, comma separated")
+	flag.StringVar(&config.BtfFile, "btf", "", "kernel BTF file for kernels without /sys/kernel/btf/vmlinux")
+	flag.IntVar(&config.RingBufferSize, "ringbuf_size", 1<<24, "size in bytes of the fentry ring buffer, a power of 2")
+	flag.IntVar(&config.PerfBufferPages, "perf_pages", 64, "pages per CPU of each perf event buffer")
+	flag.IntVar(&config.BufferWatermark, "buffer_watermark", 0, "bytes waiting in a buffer before userspace is woken up, 0 for every event")
+	flag.IntVar(&config.LostReportInterval, "lost_report_interval", 60, "seconds between reports of lost events, 0 to disable")
+	flag.StringVar(&config.LagPolicy, "lag_policy", lagDropNew, "drop_new | drop_old | exit, what to do when userspace falls behind")
 	flag.StringVar(&configPath, "c", "config.yaml", "config file path")
 	flag.Parse()
 
@@ -102,112 +242,123 @@ func initConfigs() {
 		}
 	}
 
-	ebpfType = config.EbpfType
-
-	Set_Docker_Path(config.DockerRuntime, config.DockerData)
-	if ok := Docker_Runtime_Verifier(); !ok {
-		fmt.Println("Docker Runtime Verifier failed. please use -docker_runtime and -docker_data args to specify docker env path.")
-		os.Exit(1)
+	if config.BtfFile != "" {
+		loadKernelTypes(config.BtfFile)
 	}
+	checkBufferConfig()
 
-	dockerinfo.SetDockerMode(config.K8s)
-	dockerinfo.NewLocalCaches()
-
-	outputer = NewOutputer(config.IPv6, config.Format, config.ExcludeFilter,config.LogPath)
-
-}
-
-
-func runForLocalDockerInfos(){
-	dockerinfo.RunWithInterval(10, config.DockerRuntime, config.DockerData, dockerinfo.LoadContainerInfosToCache)
-}
-
-func setupBpfFentryWorkers() {
-	err := features.HaveProgramType(ebpf.Tracing)
-	if errors.Is(err, ebpf.ErrNotSupported) {
-		log.Fatalln("tracing program type is not supported")
+	ebpfType = EBPF_PROG_TYPE(config.EbpfType)
+	if ebpfType == AUTO {
+		ebpfType = Auto_Select_Ebpf_Type()
 	}
-	if err != nil {
-		panic(err)
+	log.Printf("Using %s eBPF program type", ebpfType)
+	if scoped() && ebpfType == KPROBE {
+		log.Printf("-scope_* options need the fentry or tracepoint program type, tracing all processes")
 	}
 
-	stopper := make(chan os.Signal, 1)
-	signal.Notify(stopper, os.Interrupt, syscall.SIGTERM)
-
-	if err := rlimit.RemoveMemlock(); err != nil {
-		log.Fatal(err)
+	if config.ContainerRuntime != runtimeNone {
+		containerRuntime = dockerinfo.SelectRuntime(config.ContainerRuntime, containerRuntimes())
+		if containerRuntime == nil && config.ContainerRuntime != runtimeAuto {
+			log.Fatalf("unknown containerRuntime %q, use %s, %s, %s, %s, %s or %s", config.ContainerRuntime, runtimeAuto, runtimeNone,
+				dockerinfo.RuntimeDocker, dockerinfo.RuntimeContainerd, dockerinfo.RuntimeCrio, dockerinfo.RuntimePodman)
+		}
+		if containerRuntime != nil && !containerRuntime.Detect() {
+			fmt.Println("Container Runtime Verifier failed. please use -docker_runtime, -docker_data, -containerd_state, -crio_run, -storage_root or -storage_run_root args to specify its paths, or -runtime none to run without containers.")
+			os.Exit(1)
+		}
+	}
+	if containerRuntime == nil {
+		log.Printf("No container runtime found, running in host-only mode, container fields are left empty")
+	} else {
+		log.Printf("Using %s container runtime", containerRuntime.Name())
 	}
 
-	// Load pre-compiled programs and maps into the kernel.
-	objs := bpfObjects{}
-	if err := loadBpfObjects(&objs, nil); err != nil {
-		log.Fatalf("loading fentry objects: %v", err)
+	dockerinfo.NewLocalCaches()
+
+	if config.DnsCache {
+		hostCache = dns.NewCache(0)
 	}
-	defer objs.Close()
 
-	lnk, err := link.AttachTracing(link.TracingOptions{
-		Program:    objs.TcpConnect,
-		AttachType: ebpf.AttachTraceFEntry,
-	})
-	if err != nil {
-		log.Fatalf("attaching fentry: %v", err)
+	if config.ProcTable {
+		procTable = linux.NewProcessTable(procExitGrace, 0)
 	}
-	defer lnk.Close()
 
+	outputer = NewOutputer(config.IPv6, config.Format, config.ExcludeFilter,config.LogPath)
 
-	ringb, err := ringbuf.NewReader(objs.Events)
-	if err != nil {
-		log.Fatalf("creating ringbuf reader: %v", err)
-	}
-	defer ringb.Close()
+}
 
-	go func() {
-		<-stopper
-		log.Println("Received signal, exiting program..")
 
-		if err := ringb.Close(); err != nil {
-			log.Fatalf("closing perf event reader: %s", err)
-		}
-	}()
+// -runtime values besides the dockerinfo runtimes
+const (
+	// runtimeAuto selects the first container runtime found on the host,
+	// or host-only mode if there is none.
+	runtimeAuto = "auto"
+	// runtimeNone selects host-only mode, events have no container.
+	runtimeNone = "none"
+)
 
-	outputer.PrintHeader()
+// containerRuntimes returns the container runtimes in the order -runtime
+// auto tries them.
+func containerRuntimes() []dockerinfo.Runtime {
+	storage := dockerinfo.ContainerStorage{GraphRoot: config.StorageRoot, RunRoot: config.StorageRunRoot}
+	return []dockerinfo.Runtime{
+		dockerinfo.NewDockerInfoWithPath(config.DockerRuntime, config.DockerData),
+		dockerinfo.NewContainerdInfoWithPath(config.ContainerdState, dockerinfo.ContainerdK8sNamespace),
+		dockerinfo.NewCrioInfoWithPath(config.CrioRun, storage),
+		dockerinfo.NewPodmanInfoWithPath(storage, ""),
+	}
+}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, os.Kill)
+// Containers are reloaded as the runtime directories change, with a full
+// scan every containerReconcileInterval to catch up on missed changes.
+// Without inotify they are scanned every containerPollInterval.
+const (
+	containerReconcileInterval = time.Minute
+	containerPollInterval      = 10 * time.Second
+)
 
-	go (func() {
-		for {
-			if !readTcpEvents(ringb) {
-				return
-			}
-		}
-	})()
-	<-sig
+func runForLocalDockerInfos(){
+	dockerinfo.WatchContainers(containerRuntime, containerReconcileInterval, containerPollInterval)
 }
 
-func readTcpEvents(rb *ringbuf.Reader) bool {
-	var event TcpEvent
-	record, err := rb.Read()
-	if err != nil {
-		if errors.Is(err, perf.ErrClosed) {
-			return false
+// printEvent annotates the payload with the host name the peer address was
+// resolved from, if known, and hands it to the outputer.
+func printEvent(eventPayload EventPayload) {
+	if hostCache != nil && eventPayload.Host == "" {
+		ip := eventPayload.DestIP
+		if eventPayload.Direction == DirectionInbound {
+			ip = eventPayload.SrcIP
 		}
-		log.Printf("reading from perf event reader: %s", err)
-		return true
+		eventPayload.Host = hostCache.Lookup(eventPayload.ContainerID, ip)
 	}
+	outputer.PrintLine(eventPayload)
+}
 
-	if err := binary.Read(bytes.NewBuffer(record.RawSample), binary.LittleEndian, &event); err != nil {
-		log.Printf("parsing perf event: %s", err)
-		return true
-	}
+func newTcpEventPayload(event *TcpEvent) EventPayload {
+	eventPayload := newGenericTcpEventPayload(event)
+	setTcpEventAddrs(&eventPayload, event)
+	return eventPayload
+}
+
+// swapTcpEventAddrs turns the local/remote tuple of an inbound connection
+// into the order of accept events, the peer as source and the local address
+// as destination.
+func swapTcpEventAddrs(event *TcpEvent) {
+	event.Saddr, event.Daddr = event.Daddr, event.Saddr
+	event.Sport, event.Dport = event.Dport, event.Sport
+	event.Saddr6, event.Daddr6 = event.Daddr6, event.Saddr6
+}
 
-	eventPayload := newGenericTcpEventPayload(&event)
-	eventPayload.SrcIP = conv.ToIP4(event.Saddr)
+func setTcpEventAddrs(eventPayload *EventPayload, event *TcpEvent) {
+	if event.Af == conv.AF_INET6 {
+		eventPayload.SrcIP = conv.ToIP6(event.Saddr6[0], event.Saddr6[1])
+		eventPayload.DestIP = conv.ToIP6(event.Daddr6[0], event.Daddr6[1])
+	} else {
+		eventPayload.SrcIP = conv.ToIP4(event.Saddr)
+		eventPayload.DestIP = conv.ToIP4(event.Daddr)
+	}
 	eventPayload.SrcPort = event.Sport
-	eventPayload.DestIP = conv.ToIP4(event.Daddr)
 	eventPayload.DestPort = event.Dport
-	outputer.PrintLine(eventPayload)
-	return true
 }
 
 func newGenericTcpEventPayload(event *TcpEvent) EventPayload {
@@ -221,206 +372,101 @@ func newGenericTcpEventPayload(event *TcpEvent) EventPayload {
 
 
 
-	pid := int(event.Pid)
-	pidStr :=strconv.Itoa(pid)
-	contname:= dockerinfo.GetContainerNameFromConnProcessCacheByPid(pidStr)
-
 	payload := EventPayload{
 		// KernelTime:    strconv.Itoa(int(event.TsUs)),
 		UTime:        time.Now(),
-		AddressFamily: "v4",
+		Type:          TypeConnect,
+		Direction:     DirectionOutbound,
+		Protocol:      ProtocolTCP,
+		AddressFamily: conv.ToAddressFamily(int(event.Af)),
+		EbpfType:      ebpfType.String(),
 		Pid:           event.Pid,
-		ProcessPath:   linux.ProcessPathForPid(pid),
-		ProcessArgs:   linux.ProcessArgsForPid(pid),
 		User:          username,
 		Comm:          unix.ByteSliceToString(event.Comm[:]),
-		ConatinerName: contname,
+		Result:        conv.ToResult(int(event.Ret)),
+		Errno:         conv.ToErrno(int(event.Ret)),
 	}
+	setProcessInfo(&payload, event.TaskIDs)
 	return payload
 }
 
-func setupBpfTPWorkers() {
-	if !TP_Runtime_Verifier() {
-		log.Fatalln("tracepoint program type is not supported")
-	}
-
-	err := features.HaveProgramType(ebpf.TracePoint)
-	if errors.Is(err, ebpf.ErrNotSupported) {
-		log.Fatalln("tracepoint program type is not supported")
-	}
-	if err != nil {
-		panic(err)
-	}
-
-	stopper := make(chan os.Signal, 1)
-	signal.Notify(stopper, os.Interrupt, syscall.SIGTERM)
-
-	// Allow the current process to lock memory for eBPF resources.
-	if err := rlimit.RemoveMemlock(); err != nil {
-		log.Fatal(err)
-	}
-
-	// Load pre-compiled programs and maps into the kernel.
-	objs := bpfObjects{}
-	if err := loadBpfObjects(&objs, nil); err != nil {
-		log.Fatalf("loading tracepoint objects: %v", err)
-	}
-	defer objs.Close()
-
-	// Load eBPF program
-	tp, err := link.Tracepoint("syscalls", "sys_enter_connect", objs.TcpConnect, nil)
-	if err != nil {
-		log.Fatalf("attaching tracepoint: %s", err)
-	}
-	defer tp.Close()
-
-	rd4, err := perf.NewReader(objs.Events, os.Getpagesize())
-	if err != nil {
-		log.Fatalf("creating perf event reader: %s", err)
-	}
-	defer rd4.Close()
-
-	// rd6, err := perf.NewReader(objs.Ipv6Events, os.Getpagesize())
-	// if err != nil {
-	// 	log.Fatalf("creating perf event reader: %s", err)
-	// }
-	// defer rd6.Close()
-
-	// rdOther, err := perf.NewReader(objs.OtherSocketEvents, os.Getpagesize())
-	// if err != nil {
-	// 	log.Fatalf("creating perf event reader: %s", err)
-	// }
-	// defer rdOther.Close()
-
-	go func() {
-		<-stopper
-		log.Println("Received signal, exiting program..")
-
-		if err := rd4.Close(); err != nil {
-			log.Fatalf("closing perf event reader: %s", err)
+// setProcessInfo fills the process, parent and container of an event from
+// the ids recorded by the eBPF program, see headers/task_ids.h, and from
+// /proc for what the program couldn't record. Path and arguments come from
+// procTable first, which still knows processes that are gone. If the program
+// recorded the start time and the process now running under the PID started
+// at another time, the PID was reused and neither is used.
+func setProcessInfo(payload *EventPayload, ids TaskIDs) {
+	pid := int(payload.Pid)
+	payload.Tid = ids.Tid
+	payload.PPid = ids.PPid
+	payload.CgroupID = ids.CgroupId
+	payload.NetNs = ids.NetNs
+	payload.PidNs = ids.PidNs
+
+	startNs := ids.StartNs
+	stat, err := linux.ProcessStatForPid(pid)
+	current := err == nil && (startNs == 0 || linux.StartNsToTicks(startNs) == stat.StartTicks)
+	reused := err == nil && !current
+
+	found := false
+	if procTable != nil && !reused {
+		payload.ProcessPath, payload.ProcessArgs, found = procTable.Lookup(pid)
+	}
+	if current {
+		if startNs == 0 {
+			startNs = stat.StartTicks * (1000000000 / linux.ClockTicks)
 		}
-
-		// if err := rd6.Close(); err != nil {
-		// 	log.Fatalf("closing perf event reader: %s", err)
-		// }
-
-		// if err := rdOther.Close(); err != nil {
-		// 	log.Fatalf("closing perf event reader: %s", err)
-		// }
-	}()
-
-	outputer.PrintHeader()
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, os.Kill)
-
-	go (func() {
-		for {
-			if !readIP4Events(rd4) {
-				return
-			}
+		if payload.PPid == 0 {
+			payload.PPid = uint32(stat.PPid)
 		}
-	})()
-
-	// go (func() {
-	// 	for {
-	// 		if !readIP6Events(rd6) {
-	// 			return
-	// 		}
-	// 	}
-	// })()
-
-	// go (func() {
-	// 	for {
-	// 		if !readOtherEvents(rdOther) {
-	// 			return
-	// 		}
-	// 	}
-	// })()
-
-	<-sig
-}
-
-func readIP4Events(rd *perf.Reader) bool {
-	var event IP4Event
-	record, err := rd.Read()
-	if err != nil {
-		if errors.Is(err, perf.ErrClosed) {
-			return false
+		if payload.NetNs == 0 {
+			payload.NetNs = linux.NamespaceInodeForPid(pid, "net")
+		}
+		if payload.PidNs == 0 {
+			payload.PidNs = linux.NamespaceInodeForPid(pid, "pid")
+		}
+		if !found {
+			payload.ProcessPath = linux.ProcessPathForPid(pid)
+			payload.ProcessArgs = linux.ProcessArgsForPid(pid)
 		}
-		log.Printf("reading from perf event reader: %s", err)
-		return true
-	}
-
-	if record.LostSamples != 0 {
-		log.Printf("perf event ring buffer full, dropped %d samples", record.LostSamples)
-		return true
 	}
-
-	if err := binary.Read(bytes.NewBuffer(record.RawSample), binary.LittleEndian, &event); err != nil {
-		log.Printf("parsing perf event: %s", err)
-		return true
+	if startNs != 0 {
+		payload.ProcessStartTime = boottimeToTime(startNs)
 	}
-
-	eventPayload := newGenericEventPayload(&event.Event)
-	eventPayload.DestIP = conv.ToIP4(event.Daddr)
-	eventPayload.DestPort = event.Dport
-	outputer.PrintLine(eventPayload)
-	return true
-}
-
-func readIP6Events(rd *perf.Reader) bool {
-	var event IP6Event
-	record, err := rd.Read()
-	if err != nil {
-		if errors.Is(err, perf.ErrClosed) {
-			return false
+	if payload.PPid != 0 {
+		parentFound := false
+		if procTable != nil {
+			payload.ParentPath, _, parentFound = procTable.Lookup(int(payload.PPid))
+		}
+		// A parent started before its child, a later one reused the PID.
+		parent, err := linux.ProcessStatForPid(int(payload.PPid))
+		if !parentFound && err == nil && (startNs == 0 || parent.StartTicks <= linux.StartNsToTicks(startNs)) {
+			payload.ParentPath = linux.ProcessPathForPid(int(payload.PPid))
 		}
-		log.Printf("reading from perf event reader: %s", err)
-		return true
 	}
 
-	if record.LostSamples != 0 {
-		log.Printf("perf event ring buffer full, dropped %d samples", record.LostSamples)
-		return true
+	// Container fields are left empty in host-only mode.
+	if containerRuntime == nil {
+		return
 	}
-
-	if err := binary.Read(bytes.NewBuffer(record.RawSample), binary.LittleEndian, &event); err != nil {
-		log.Printf("parsing perf event: %s", err)
-		return true
+	payload.ConatinerName = dockerinfo.NoContainerName
+	if info := dockerinfo.LookupContainer(ids.CgroupId, strconv.Itoa(pid), linux.StartNsToTicks(startNs)); info != nil {
+		payload.ConatinerName = info.Name
+		payload.ContainerID = info.ID
+		payload.PodName = info.PodName
+		payload.PodNamespace = info.PodNamespace
 	}
-
-	eventPayload := newGenericEventPayload(&event.Event)
-	eventPayload.DestIP = conv.ToIP6(event.Daddr1, event.Daddr2)
-	eventPayload.DestPort = event.Dport
-	outputer.PrintLine(eventPayload)
-	return true
 }
 
-func readOtherEvents(rd *perf.Reader) bool {
-	var event OtherSocketEvent
-	record, err := rd.Read()
-	if err != nil {
-		if errors.Is(err, perf.ErrClosed) {
-			return false
-		}
-		log.Printf("reading from perf event reader: %s", err)
-		return true
+// boottimeToTime converts a CLOCK_BOOTTIME timestamp in nanoseconds to wall
+// clock time.
+func boottimeToTime(ns uint64) time.Time {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_BOOTTIME, &ts); err != nil {
+		return time.Now()
 	}
-
-	if record.LostSamples != 0 {
-		log.Printf("perf event ring buffer full, dropped %d samples", record.LostSamples)
-		return true
-	}
-
-	if err := binary.Read(bytes.NewBuffer(record.RawSample), binary.LittleEndian, &event); err != nil {
-		log.Printf("parsing perf event: %s", err)
-		return true
-	}
-
-	eventPayload := newGenericEventPayload(&event.Event)
-	outputer.PrintLine(eventPayload)
-	return true
+	return time.Now().Add(time.Duration(ns) - time.Duration(ts.Nano()))
 }
 
 func newGenericEventPayload(event *Event) EventPayload {
@@ -434,21 +480,21 @@ func newGenericEventPayload(event *Event) EventPayload {
 
 
 
-	pid := int(event.Pid)
-	pidStr :=strconv.Itoa(pid)
-	contname:= dockerinfo.GetContainerNameFromConnProcessCacheByPid(pidStr)
-
 	payload := EventPayload{
 		// KernelTime:    strconv.Itoa(int(event.TsUs)),
 		UTime:        time.Now(),
+		Type:          TypeConnect,
+		Direction:     DirectionOutbound,
+		Protocol:      ProtocolTCP,
 		AddressFamily: conv.ToAddressFamily(int(event.Af)),
+		EbpfType:      ebpfType.String(),
 		Pid:           event.Pid,
-		ProcessPath:   linux.ProcessPathForPid(pid),
-		ProcessArgs:   linux.ProcessArgsForPid(pid),
 		User:          username,
 		Comm:          unix.ByteSliceToString(event.Task[:]),
-		ConatinerName: contname,
+		Result:        conv.ToResult(int(event.Ret)),
+		Errno:         conv.ToErrno(int(event.Ret)),
 	}
+	setProcessInfo(&payload, event.TaskIDs)
 	return payload
 }
 
@@ -78,16 +78,54 @@ func (l logFileOutput) PrintLine(e EventPayload) {
 	}
 
 	logF:= log.Fields{
+		"type": e.Type,
+		"direction": e.Direction,
+		"protocol": e.Protocol,
 		"user": e.User,
 		"pid": strconv.Itoa(int(e.Pid)),
+		"tid": strconv.Itoa(int(e.Tid)),
+		"ppid": strconv.Itoa(int(e.PPid)),
 		"procPath":e.ProcessPath,
 		"procArgs": e.ProcessArgs,
+		"parentPath": e.ParentPath,
+		"procStartTime": e.ProcessStartTime,
 		"ipv6": ipv6,
 		"sip":e.SrcIP.String(),
 		"sport": strconv.Itoa(int(e.SrcPort)),
 		"dip": e.DestIP.String(),
 		"dport": strconv.Itoa(int(e.DestPort)),
+		"socketPath": e.SocketPath,
+		"host": e.Host,
+		"result": e.Result,
+		"suppressed": e.Suppressed,
+		"state": e.State,
+		"oldState": e.OldState,
+		"qname": e.QName,
+		"qtype": e.QType,
 		"conatiner": e.ConatinerName,
+		"pod": e.PodName,
+		"podNamespace": e.PodNamespace,
+		"cgroupId": e.CgroupID,
+		"netns": e.NetNs,
+		"pidns": e.PidNs,
+		"ebpfType": e.EbpfType,
+	}
+
+	if e.Flow != nil {
+		logF["startTime"] = e.Flow.StartTime
+		logF["endTime"] = e.Flow.EndTime
+		logF["durationMs"] = e.Flow.DurationMs
+		logF["txBytes"] = e.Flow.TxBytes
+		logF["rxBytes"] = e.Flow.RxBytes
+		logF["txPackets"] = e.Flow.TxPackets
+		logF["rxPackets"] = e.Flow.RxPackets
+	}
+
+	if e.Summary != nil {
+		logF["count"] = e.Summary.Count
+		logF["failed"] = e.Summary.Failed
+		logF["firstSeen"] = e.Summary.FirstSeen
+		logF["lastSeen"] = e.Summary.LastSeen
 	}
 
 	l.logger.WithFields(logF).Info("ebpf")
@@ -138,13 +176,17 @@ func (t tableOutput) PrintHeader() {
 	var header string
 	var args []interface{}
 
-	header = "%-9s %-10s %-9s %-6s %-20s %-20s %-15s %s\n"
-	args = []interface{}{"TIME", "USER", "PID", "AF","SRC", "DEST","CONTAINER", "PROCESS"}
+	header = "%-9s %-10s %-9s %-9s %-12s %-6s %-6s %-9s %-20s %-20s %-30s %-12s %-13s %-30s %-15s %s\n"
+	args = []interface{}{"TIME", "USER", "PID", "PPID", "TYPE", "AF","PROTO","DIR","SRC", "DEST","HOST","STATE","RESULT","QUERY","CONTAINER", "PROCESS"}
 
 	fmt.Printf(header, args...)
 }
 
 func (t tableOutput) PrintLine(e EventPayload) {
+	// Flow records have no columns here, use the json or logfile output.
+	if e.Type == TypeFlow {
+		return
+	}
 	// fmt.Println("debug: ",e)
 	if t.excludeParam != "" {
 		filter := filter.ParseExcludeParam(t.excludeParam)
@@ -153,8 +195,22 @@ func (t tableOutput) PrintLine(e EventPayload) {
 		}
 	}
 	time := e.UTime.Format("15:04:05")
+	query := ""
+	if e.QName != "" {
+		query = e.QType + " " + e.QName
+	}
 	dest := e.DestIP.String() + " " + strconv.Itoa(int(e.DestPort))
 	src :=  e.SrcIP.String() + " " + strconv.Itoa(int(e.SrcPort))
+	if e.AddressFamily == "AF_UNIX" {
+		dest = e.SocketPath
+		src = ""
+	}
+	result := e.Result
+	if e.Summary != nil {
+		// Summaries have no source, the kernel counted connects from any port.
+		src = ""
+		result = "count=" + strconv.FormatUint(e.Summary.Count, 10)
+	}
 
 	var line string
 	var args []interface{}
@@ -163,6 +219,9 @@ func (t tableOutput) PrintLine(e EventPayload) {
 	// if (e.AddressFamily == "AF_INET"){
 	// 	addrFamily = "ipv4"
 	// }
+	if (e.AddressFamily == "AF_UNIX"){
+		addrFamily = "unix"
+	}
 	if (e.AddressFamily == "AF_INET6"){ 
 		addrFamily = "ipv6"
 		if !t.ipv6 {
@@ -170,8 +229,8 @@ func (t tableOutput) PrintLine(e EventPayload) {
 		}
 	}
 
-	line = "%-9s %-10s %-9d %-6s %-20s %-20s %-15s %s\n"
-	args = []interface{}{time, e.User, e.Pid, addrFamily,src, dest,e.ConatinerName,e.ProcessPath + " " + e.ProcessArgs}
+	line = "%-9s %-10s %-9d %-9d %-12s %-6s %-6s %-9s %-20s %-20s %-30s %-12s %-13s %-30s %-15s %s\n"
+	args = []interface{}{time, e.User, e.Pid, e.PPid, e.Type, addrFamily,e.Protocol,e.Direction,src, dest,e.Host,e.State,result,query,e.ConatinerName,e.ProcessPath + " " + e.ProcessArgs}
 
 
 	fmt.Printf(line, args...)
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"net"
 	"os"
 	"testing"
 	"time"
@@ -70,6 +71,44 @@ func TestJsonOutput_PrintLine(t *testing.T) {
 	}
 }
 
+func TestJsonOutput_PrintLineFlow(t *testing.T) {
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	start := time.Now().Add(-time.Second)
+	outputer := &jsonOutput{}
+	outputer.PrintLine(EventPayload{
+		AddressFamily: "AF_INET",
+		Type:          TypeFlow,
+		Flow: &Flow{
+			StartTime:  start,
+			EndTime:    start.Add(time.Second),
+			DurationMs: 1000,
+			TxBytes:    1500,
+			RxBytes:    64000,
+			TxPackets:  3,
+			RxPackets:  45,
+		},
+	})
+
+	w.Close()
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+
+	var result EventPayload
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+	if assert.NotNil(t, result.Flow) {
+		assert.Equal(t, uint64(1500), result.Flow.TxBytes)
+		assert.Equal(t, uint64(64000), result.Flow.RxBytes)
+		assert.Equal(t, uint32(45), result.Flow.RxPackets)
+		assert.Equal(t, int64(1000), result.Flow.DurationMs)
+	}
+	assert.NotContains(t, buf.String(), "null")
+}
+
 func TestTableOutput_PrintLine(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -82,14 +121,24 @@ func TestTableOutput_PrintLine(t *testing.T) {
 			UTime:       time.Now(),
 			User:         "test",
 			Pid:          123,
+			PPid:         4567,
 			DestIP:       []byte{127, 0, 0, 1},
 			DestPort:     8080,
 			ProcessPath:  "/bin/test",
 			ProcessArgs:  "arg1 arg2",
+			Result:       "ECONNREFUSED",
+			Direction:    DirectionInbound,
+			Protocol:     ProtocolUDP,
+			Host:         "db.internal.corp",
 		}, true},
 		{"ipv6 event with ipv6 disabled", false, EventPayload{
 			AddressFamily: "AF_INET6",
 		}, false},
+		{"flow record", false, EventPayload{
+			AddressFamily: "AF_INET",
+			Type:          TypeFlow,
+			Flow:          &Flow{TxBytes: 1},
+		}, false},
 	}
 
 	for _, tt := range tests {
@@ -110,6 +159,11 @@ func TestTableOutput_PrintLine(t *testing.T) {
 			if tt.shouldPrint {
 				assert.Contains(t, buf.String(), "test")
 				assert.Contains(t, buf.String(), "8080")
+				assert.Contains(t, buf.String(), "ECONNREFUSED")
+				assert.Contains(t, buf.String(), DirectionInbound)
+				assert.Contains(t, buf.String(), ProtocolUDP)
+				assert.Contains(t, buf.String(), "db.internal.corp")
+				assert.Contains(t, buf.String(), "4567")
 			} else {
 				assert.Empty(t, buf.String())
 			}
@@ -134,4 +188,124 @@ func TestTableOutput_PrintHeader(t *testing.T) {
 	assert.Contains(t, buf.String(), "TIME")
 	assert.Contains(t, buf.String(), "USER")
 	assert.Contains(t, buf.String(), "PID")
-}
\ No newline at end of file
+	assert.Contains(t, buf.String(), "PPID")
+	assert.Contains(t, buf.String(), "STATE")
+	assert.Contains(t, buf.String(), "DIR")
+	assert.Contains(t, buf.String(), "PROTO")
+	assert.Contains(t, buf.String(), "QUERY")
+	assert.Contains(t, buf.String(), "HOST")
+	assert.Contains(t, buf.String(), "TYPE")
+}
+
+func TestTableOutput_PrintLineDns(t *testing.T) {
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	outputer := &tableOutput{}
+	outputer.PrintLine(EventPayload{
+		AddressFamily: "AF_INET",
+		Type:          TypeDNS,
+		Protocol:      ProtocolUDP,
+		DestIP:        []byte{10, 0, 0, 2},
+		DestPort:      53,
+		QName:         "www.example.com",
+		QType:         "AAAA",
+	})
+
+	w.Close()
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+
+	assert.Contains(t, buf.String(), "AAAA www.example.com")
+	assert.Contains(t, buf.String(), TypeDNS)
+}
+func TestTableOutput_PrintLineUnix(t *testing.T) {
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	outputer := &tableOutput{}
+	outputer.PrintLine(EventPayload{
+		AddressFamily: "AF_UNIX",
+		Type:          TypeConnect,
+		Protocol:      ProtocolUnix,
+		SocketPath:    "/var/run/docker.sock",
+		ProcessPath:   "/usr/bin/docker",
+	})
+
+	w.Close()
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+
+	assert.Contains(t, buf.String(), "/var/run/docker.sock")
+	assert.Contains(t, buf.String(), "unix")
+	assert.NotContains(t, buf.String(), "<nil>")
+}
+
+func TestTableOutput_PrintLineSummary(t *testing.T) {
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	outputer := &tableOutput{}
+	outputer.PrintLine(EventPayload{
+		AddressFamily: "AF_INET",
+		Type:          TypeSummary,
+		Protocol:      ProtocolTCP,
+		DestIP:        net.ParseIP("10.0.0.1"),
+		DestPort:      443,
+		Summary: &Summary{
+			Count:  1200,
+			Failed: 3,
+		},
+	})
+
+	w.Close()
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+
+	assert.Contains(t, buf.String(), "10.0.0.1 443")
+	assert.Contains(t, buf.String(), "count=1200")
+	assert.NotContains(t, buf.String(), "<nil>")
+}
+
+func TestJsonOutput_PrintLineSummary(t *testing.T) {
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	first := time.Now().Add(-10 * time.Second)
+	outputer := &jsonOutput{}
+	outputer.PrintLine(EventPayload{
+		AddressFamily: "AF_INET",
+		Type:          TypeSummary,
+		Summary: &Summary{
+			Count:     1200,
+			Failed:    3,
+			FirstSeen: first,
+			LastSeen:  first.Add(9 * time.Second),
+		},
+	})
+
+	w.Close()
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+
+	var result EventPayload
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+	if assert.NotNil(t, result.Summary) {
+		assert.Equal(t, uint64(1200), result.Summary.Count)
+		assert.Equal(t, uint64(3), result.Summary.Failed)
+		assert.Equal(t, 9*time.Second, result.Summary.LastSeen.Sub(result.Summary.FirstSeen))
+	}
+	assert.NotContains(t, buf.String(), `"flow"`)
+}
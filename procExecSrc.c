@@ -0,0 +1,174 @@
+// +build ignore
+
+#include "vmlinux_compact_common.h"
+#include "bpf_helpers.h"
+
+#define FILENAME_LEN 256
+#define ARG_LEN 128
+#define MAX_ARGS 16
+#define ARGS_BUF (ARG_LEN * MAX_ARGS)
+
+#define PROC_EXEC 1
+#define PROC_EXIT 2
+
+#ifndef BPF_F_NO_PREALLOC
+#define BPF_F_NO_PREALLOC (1U << 0)
+#endif
+
+char LICENSE[] SEC("license") = "Dual MIT/GPL";
+
+/**
+ * Keeps userspace's process table up to date, so events of short-lived
+ * processes can be enriched after the process is gone from /proc.
+ *
+ * argv is copied from the user stack when execve/execveat is entered and
+ * kept in execs, keyed by tgid, until sched_process_exec reports that the
+ * exec succeeded. sched_process_exit reports when the main thread exits.
+ */
+struct proc_header {
+    __u32 kind;
+    __u32 pid;
+    __u64 ts_us;
+    __u32 args_len;   // bytes used in args
+    __u32 args_count; // number of NUL terminated strings in args
+};
+
+struct proc_event {
+    struct proc_header hdr;
+    char filename[FILENAME_LEN];
+    char args[ARGS_BUF];
+};
+
+struct sys_enter_execve_args {
+    __u64 pad[2];
+    __u64 filename;
+    __u64 argv;
+    __u64 envp;
+};
+
+struct sys_enter_execveat_args {
+    __u64 pad[2];
+    __u64 fd;
+    __u64 filename;
+    __u64 argv;
+    __u64 envp;
+    __u64 flags;
+};
+
+struct sched_process_exec_args {
+    __u64 pad;
+    __u32 filename_loc; // __data_loc char[] filename
+    __s32 pid;
+    __s32 old_pid;
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+} proc_events SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 1024);
+    __type(key, u32);
+    __type(value, struct proc_event);
+    __uint(map_flags, BPF_F_NO_PREALLOC);
+} execs SEC(".maps");
+
+// struct proc_event is too large for the BPF stack.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, u32);
+    __type(value, struct proc_event);
+} proc_heap SEC(".maps");
+
+static __always_inline int save_argv(__u64 argv) {
+    u32 zero = 0;
+    struct proc_event *ev = bpf_map_lookup_elem(&proc_heap, &zero);
+    if (!ev) {
+        return 0;
+    }
+    ev->hdr.args_len = 0;
+    ev->hdr.args_count = 0;
+
+#pragma unroll
+    for (int i = 0; i < MAX_ARGS; i++) {
+        __u64 argp = 0;
+        if (bpf_probe_read_user(&argp, sizeof(argp), (void *)(argv + i * sizeof(__u64))) < 0 || !argp) {
+            break;
+        }
+        __u32 len = ev->hdr.args_len;
+        if (len > ARGS_BUF - ARG_LEN) {
+            break;
+        }
+        long n = bpf_probe_read_user_str(&ev->args[len], ARG_LEN, (void *)argp);
+        if (n <= 0) {
+            break;
+        }
+        ev->hdr.args_len = len + n;
+        ev->hdr.args_count++;
+    }
+
+    u32 tgid = bpf_get_current_pid_tgid() >> 32;
+    bpf_map_update_elem(&execs, &tgid, ev, BPF_ANY);
+    return 0;
+}
+
+SEC("tracepoint/syscalls/sys_enter_execve")
+int sys_enter_execve(struct sys_enter_execve_args *ctx) {
+    return save_argv(ctx->argv);
+}
+
+SEC("tracepoint/syscalls/sys_enter_execveat")
+int sys_enter_execveat(struct sys_enter_execveat_args *ctx) {
+    return save_argv(ctx->argv);
+}
+
+SEC("tracepoint/sched/sched_process_exec")
+int sched_process_exec(struct sched_process_exec_args *ctx) {
+    u32 tgid = bpf_get_current_pid_tgid() >> 32;
+    struct proc_event *ev = bpf_map_lookup_elem(&execs, &tgid);
+    if (!ev) {
+        // Not started by execve/execveat, e.g. a usermode helper.
+        u32 zero = 0;
+        ev = bpf_map_lookup_elem(&proc_heap, &zero);
+        if (!ev) {
+            return 0;
+        }
+        ev->hdr.args_len = 0;
+        ev->hdr.args_count = 0;
+    }
+
+    ev->hdr.kind = PROC_EXEC;
+    ev->hdr.pid = tgid;
+    ev->hdr.ts_us = bpf_ktime_get_ns() / 1000;
+    bpf_probe_read_kernel_str(ev->filename, sizeof(ev->filename), (void *)ctx + (ctx->filename_loc & 0xFFFF));
+
+    __u32 size = sizeof(struct proc_header) + FILENAME_LEN + ev->hdr.args_len;
+    if (size > sizeof(struct proc_event)) {
+        size = sizeof(struct proc_event);
+    }
+    bpf_perf_event_output(ctx, &proc_events, BPF_F_CURRENT_CPU, ev, size);
+    bpf_map_delete_elem(&execs, &tgid);
+    return 0;
+}
+
+SEC("tracepoint/sched/sched_process_exit")
+int sched_process_exit(void *ctx) {
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    u32 tgid = pid_tgid >> 32;
+    if ((u32)pid_tgid != tgid) {
+        // Another thread than the main one.
+        return 0;
+    }
+    bpf_map_delete_elem(&execs, &tgid);
+
+    struct proc_header hdr = {};
+    hdr.kind = PROC_EXIT;
+    hdr.pid = tgid;
+    hdr.ts_us = bpf_ktime_get_ns() / 1000;
+    bpf_perf_event_output(ctx, &proc_events, BPF_F_CURRENT_CPU, &hdr, sizeof(hdr));
+    return 0;
+}
@@ -0,0 +1,123 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	. "github.com/gotoolkits/lightmon/event"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+	"golang.org/x/sys/unix"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 procexec procExecSrc.c -- -Iheaders/
+
+// proc event kinds, see procExecSrc.c
+const (
+	kindExec = 1
+	kindExit = 2
+)
+
+// procFilenameLen is FILENAME_LEN in procExecSrc.c.
+const procFilenameLen = 256
+
+func setupBpfProcWorkers() {
+	if !ProcExec_Runtime_Verifier() {
+		log.Fatalln("sched or syscalls tracepoints are not supported")
+	}
+
+	// Load pre-compiled programs and maps into the kernel.
+	objs := procexecObjects{}
+	if err := loadProcexecObjects(&objs, collectionOptions()); err != nil {
+		log.Fatalf("loading proc objects: %v", err)
+	}
+	addCloser(&objs)
+
+	tracepoints := []struct {
+		group string
+		name  string
+		prog  *ebpf.Program
+	}{
+		{"syscalls", "sys_enter_execve", objs.SysEnterExecve},
+		{"syscalls", "sys_enter_execveat", objs.SysEnterExecveat},
+		{"sched", "sched_process_exec", objs.SchedProcessExec},
+		{"sched", "sched_process_exit", objs.SchedProcessExit},
+	}
+	for _, t := range tracepoints {
+		tp, err := link.Tracepoint(t.group, t.name, t.prog, nil)
+		if err != nil {
+			log.Fatalf("attaching tracepoint %s: %s", t.name, err)
+		}
+		addCloser(tp)
+	}
+
+	rd := newPerfReader("procexec", objs.ProcEvents)
+
+	go (func() {
+		for {
+			if !readProcEvents(rd) {
+				return
+			}
+		}
+	})()
+}
+
+func readProcEvents(rd *perf.Reader) bool {
+	var event ProcEvent
+	sample, ok := readPerfSample(rd, "procexec")
+	if !ok {
+		return false
+	}
+	if sample == nil || !decodeSample("procexec", sample, &event) {
+		return true
+	}
+	data := sample[binary.Size(event):]
+
+	pid := int(event.Pid)
+	switch event.Kind {
+	case kindExec:
+		path, args := parseProcExec(&event, data)
+		procTable.Exec(pid, execPath(pid, path), args)
+	case kindExit:
+		procTable.Exit(pid)
+	}
+	return true
+}
+
+// parseProcExec returns the file name and the arguments, without argv[0]
+// and joined like linux.ProcessArgsForPid does, of an exec event.
+func parseProcExec(event *ProcEvent, data []byte) (string, string) {
+	if len(data) < procFilenameLen {
+		return "", ""
+	}
+	filename := unix.ByteSliceToString(data[:procFilenameLen])
+
+	args := data[procFilenameLen:]
+	if int(event.ArgsLen) < len(args) {
+		args = args[:event.ArgsLen]
+	}
+	argv := strings.Split(strings.TrimRight(string(args), "\x00"), "\x00")
+	if len(argv) < 2 {
+		return filename, ""
+	}
+	return filename, strings.Join(argv[1:], " ")
+}
+
+// execPath resolves the file name passed to execve, which may be relative
+// or a symlink, through /proc/<pid>/exe while the process still exists.
+// Otherwise the file name is returned as is, it is relative to the cwd and
+// root of the process, not of lightmon.
+func execPath(pid int, filename string) string {
+	if exe, err := os.Readlink("/proc/" + strconv.Itoa(pid) + "/exe"); err == nil {
+		return exe
+	}
+	return filename
+}
@@ -1,44 +1,61 @@
+//go:build linux
+// +build linux
+
 package main
 
 import (
 	"bufio"
 	"fmt"
+	"log"
 	"os"
 	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/features"
 )
 
 var (
 	SYS_ENTER_CONNECT string  = "/sys/kernel/debug/tracing/events/syscalls/sys_enter_connect"
-	DOCKER_RUNTIME_DIR string = "/run/docker"
-	DOCKER_DATA_DIR string = "/data/docker"
+	KERNEL_BTF string = "/sys/kernel/btf/vmlinux"
+	INET_SOCK_SET_STATE string = "/sys/kernel/debug/tracing/events/sock/inet_sock_set_state"
+	TCP_EVENTS string = "/sys/kernel/debug/tracing/events/tcp"
+	SCHED_EVENTS string = "/sys/kernel/debug/tracing/events/sched"
 )
 
-func Set_Docker_Path(docker_rumtime,docker_data string) {
-	DOCKER_RUNTIME_DIR = docker_rumtime
-	DOCKER_DATA_DIR = docker_data
+func TP_Runtime_Verifier() bool{
+	if ok,err:=PathExists(SYS_ENTER_CONNECT);!ok{
+			fmt.Println("ERROR: ",err)
+			return false
+	}
+	return true
 }
 
-func Docker_Runtime_Verifier() bool{
-	if ok,err:=PathExists(DOCKER_RUNTIME_DIR);!ok{
-		fmt.Println("ERROR: ",err)
-		return false
-		
+func TcpState_Runtime_Verifier() bool{
+	if ok,err:=PathExists(INET_SOCK_SET_STATE);!ok{
+			fmt.Println("ERROR: ",err)
+			return false
 	}
+	return true
+}
 
-	if ok,err:=PathExists(DOCKER_DATA_DIR);!ok{
-		fmt.Println("ERROR: ",err)
-		return false
+func TcpRetrans_Runtime_Verifier() bool{
+	for _, name := range []string{"tcp_retransmit_skb", "tcp_send_reset", "tcp_receive_reset"} {
+		if ok,err:=PathExists(TCP_EVENTS + "/" + name);!ok{
+			fmt.Println("ERROR: ",err)
+			return false
+		}
 	}
-
-	return true
+	return TcpState_Runtime_Verifier()
 }
 
-func TP_Runtime_Verifier() bool{
-	if ok,err:=PathExists(SYS_ENTER_CONNECT);!ok{
+func ProcExec_Runtime_Verifier() bool{
+	for _, name := range []string{"sched_process_exec", "sched_process_exit"} {
+		if ok,err:=PathExists(SCHED_EVENTS + "/" + name);!ok{
 			fmt.Println("ERROR: ",err)
 			return false
+		}
 	}
-	return true
+	return TP_Runtime_Verifier()
 }
 
 func Tracing_Runtime_Verifier(fentryFn string) bool{
@@ -49,6 +66,40 @@ func Tracing_Runtime_Verifier(fentryFn string) bool{
 	return true
 }
 
+func Kprobe_Runtime_Verifier(kprobeFn string) bool{
+	if ok,err:=isFunctionAvailable(kprobeFn);!ok {
+		fmt.Println("ERROR: ",err)
+		return false
+	}
+	return true
+}
+
+// Auto_Select_Ebpf_Type probes the running kernel and returns the best
+// supported program type: fentry, then the syscalls tracepoint, then kprobes.
+// fentry uses CO-RE and needs the kernel's own BTF to attach. The tracepoint
+// program only reads the task IDs with CO-RE and also loads without BTF.
+func Auto_Select_Ebpf_Type() EBPF_PROG_TYPE {
+	if err := features.HaveProgramType(ebpf.Tracing); err != nil {
+		log.Printf("fentry unavailable: tracing program type is not supported: %v", err)
+	} else if ok, _ := PathExists(KERNEL_BTF); !ok {
+		log.Printf("fentry unavailable: %s not found", KERNEL_BTF)
+	} else if !Tracing_Runtime_Verifier("inet_stream_connect") {
+		log.Printf("fentry unavailable: inet_stream_connect not found in /proc/kallsyms")
+	} else {
+		return FENTRY
+	}
+
+	if err := features.HaveProgramType(ebpf.TracePoint); err != nil {
+		log.Printf("tracepoint unavailable: tracepoint program type is not supported: %v", err)
+	} else if !TP_Runtime_Verifier() {
+		log.Printf("tracepoint unavailable: %s not found", SYS_ENTER_CONNECT)
+	} else {
+		return TRACEPOINT
+	}
+
+	return KPROBE
+}
+
 func PathExists(path string) (bool, error) {
 	_, err := os.Stat(path)
 	if err == nil {
@@ -0,0 +1,137 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Reasons an event can be lost before it is printed.
+const (
+	// ReasonPerfOverflow counts samples the kernel dropped because a per
+	// CPU perf buffer was full.
+	ReasonPerfOverflow = "perf_overflow"
+	// ReasonRingbufFull counts events a program could not reserve in a
+	// full ring buffer.
+	ReasonRingbufFull = "ringbuf_full"
+	// ReasonDecode counts records that could not be parsed.
+	ReasonDecode = "decode"
+	// ReasonLag counts records discarded in userspace by the drop_old
+	// lag policy.
+	ReasonLag = "lag"
+	// ReasonSummaryFull counts connects a program could not count in a
+	// full summary map.
+	ReasonSummaryFull = "summary_full"
+)
+
+// lostKey identifies a lost event counter. CPU is -1 when the CPU is unknown.
+type lostKey struct {
+	source string
+	reason string
+	cpu    int
+}
+
+// LostCounter counts lost events per source (the eBPF program or buffer),
+// reason and CPU.
+type LostCounter struct {
+	mu       sync.Mutex
+	total    map[lostKey]uint64
+	reported map[lostKey]uint64
+}
+
+func NewLostCounter() *LostCounter {
+	return &LostCounter{
+		total:    make(map[lostKey]uint64),
+		reported: make(map[lostKey]uint64),
+	}
+}
+
+// Add adds n lost events.
+func (c *LostCounter) Add(source, reason string, cpu int, n uint64) {
+	if n == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.total[lostKey{source, reason, cpu}] += n
+}
+
+// Set sets the number of lost events to a total counted elsewhere, e.g. in
+// a BPF map. Totals lower than the current one are ignored.
+func (c *LostCounter) Set(source, reason string, cpu int, total uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := lostKey{source, reason, cpu}
+	if total > c.total[k] {
+		c.total[k] = total
+	}
+}
+
+// Total returns the number of events lost since start.
+func (c *LostCounter) Total() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n uint64
+	for _, v := range c.total {
+		n += v
+	}
+	return n
+}
+
+// Report returns one line per source and reason with the events lost since
+// the previous report, broken down by CPU, e.g.
+//
+//	fentry ringbuf_full: 120 lost (340 total) cpu0=100 cpu3=20
+//
+// It returns nil if nothing was lost since the previous report.
+func (c *LostCounter) Report() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	type group struct {
+		source, reason string
+	}
+	type line struct {
+		delta, total uint64
+		cpus         map[int]uint64
+	}
+	lines := make(map[group]*line)
+	for k, total := range c.total {
+		g := group{k.source, k.reason}
+		l := lines[g]
+		if l == nil {
+			l = &line{cpus: make(map[int]uint64)}
+			lines[g] = l
+		}
+		delta := total - c.reported[k]
+		l.total += total
+		l.delta += delta
+		if delta > 0 {
+			l.cpus[k.cpu] += delta
+		}
+		c.reported[k] = total
+	}
+
+	var out []string
+	for g, l := range lines {
+		if l.delta == 0 {
+			continue
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s %s: %d lost (%d total)", g.source, g.reason, l.delta, l.total)
+		cpus := make([]int, 0, len(l.cpus))
+		for cpu := range l.cpus {
+			if cpu >= 0 {
+				cpus = append(cpus, cpu)
+			}
+		}
+		sort.Ints(cpus)
+		for _, cpu := range cpus {
+			fmt.Fprintf(&b, " cpu%d=%d", cpu, l.cpus[cpu])
+		}
+		out = append(out, b.String())
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,48 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLostCounter_Report(t *testing.T) {
+	c := NewLostCounter()
+	assert.Nil(t, c.Report())
+
+	c.Add("tp_ipv4", ReasonPerfOverflow, 3, 20)
+	c.Add("tp_ipv4", ReasonPerfOverflow, 0, 100)
+	c.Add("tp_ipv4", ReasonDecode, -1, 1)
+	c.Add("tcpstate", ReasonPerfOverflow, 1, 0)
+
+	assert.Equal(t, []string{
+		"tp_ipv4 decode: 1 lost (1 total)",
+		"tp_ipv4 perf_overflow: 120 lost (120 total) cpu0=100 cpu3=20",
+	}, c.Report())
+	assert.Nil(t, c.Report())
+
+	c.Add("tp_ipv4", ReasonPerfOverflow, 3, 5)
+	assert.Equal(t, []string{
+		"tp_ipv4 perf_overflow: 5 lost (125 total) cpu3=5",
+	}, c.Report())
+	assert.Equal(t, uint64(126), c.Total())
+}
+
+func TestLostCounter_Set(t *testing.T) {
+	c := NewLostCounter()
+
+	c.Set("fentry", ReasonRingbufFull, 2, 7)
+	assert.Equal(t, []string{
+		"fentry ringbuf_full: 7 lost (7 total) cpu2=7",
+	}, c.Report())
+
+	// Kernel counters only grow, the same total is not reported again.
+	c.Set("fentry", ReasonRingbufFull, 2, 7)
+	c.Set("fentry", ReasonRingbufFull, 2, 3)
+	assert.Nil(t, c.Report())
+
+	c.Set("fentry", ReasonRingbufFull, 2, 9)
+	assert.Equal(t, []string{
+		"fentry ringbuf_full: 2 lost (9 total) cpu2=2",
+	}, c.Report())
+}
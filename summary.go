@@ -0,0 +1,152 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gotoolkits/lightmon/conv"
+	. "github.com/gotoolkits/lightmon/event"
+	"github.com/gotoolkits/lightmon/stats"
+
+	"github.com/cilium/ebpf"
+)
+
+// protoTCP is the proto of the summary keys of TCP connects, see
+// headers/summary.h
+const protoTCP = 6
+
+// setSummarySpec switches the connect programs of spec to counting connects
+// in conn_summary when -summary is set. It must be called before the spec is
+// loaded.
+func setSummarySpec(spec *ebpf.CollectionSpec) {
+	if config.SummaryInterval <= 0 {
+		return
+	}
+	spec.Maps["summary_config"].Contents = []ebpf.MapKV{{Key: uint32(0), Value: uint32(1)}}
+}
+
+// startSummary drains the summary map of source every -summary seconds, and
+// once more on exit. drops is the per CPU array the programs count the
+// connects that found the map full in.
+func startSummary(source string, summary *ebpf.Map, drops *ebpf.Map) {
+	if config.SummaryInterval <= 0 {
+		return
+	}
+	addKernelDrops(source, stats.ReasonSummaryFull, drops)
+
+	d := &summaryDrainer{source: source, m: summary, done: make(chan struct{})}
+	addCloser(d)
+	go d.run(time.Duration(config.SummaryInterval) * time.Second)
+}
+
+type summaryDrainer struct {
+	source string
+	m      *ebpf.Map
+	done   chan struct{}
+	mu     sync.Mutex
+}
+
+func (d *summaryDrainer) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.drain()
+		case <-d.done:
+			return
+		}
+	}
+}
+
+// Close prints the connects counted since the last drain. It is called
+// before the programs and maps are closed.
+func (d *summaryDrainer) Close() error {
+	close(d.done)
+	d.drain()
+	return nil
+}
+
+// drain prints one summary record per key and removes it, so the next
+// interval starts counting from zero.
+func (d *summaryDrainer) drain() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var (
+		key   SummaryKey
+		value SummaryValue
+		keys  []SummaryKey
+	)
+	iter := d.m.Iterate()
+	for iter.Next(&key, &value) {
+		keys = append(keys, key)
+	}
+	if err := iter.Err(); err != nil {
+		log.Printf("iterating %s summary: %v", d.source, err)
+	}
+
+	for _, k := range keys {
+		err := d.m.LookupAndDelete(&k, &value)
+		if errors.Is(err, ebpf.ErrNotSupported) {
+			// Hash maps support lookup and delete since 5.14. Connects
+			// counted between both calls are lost.
+			if err = d.m.Lookup(&k, &value); err == nil {
+				err = d.m.Delete(&k)
+			}
+		}
+		if errors.Is(err, ebpf.ErrKeyNotExist) {
+			continue
+		}
+		if err != nil {
+			log.Printf("draining %s summary: %v", d.source, err)
+			continue
+		}
+		printEvent(newSummaryPayload(&k, &value))
+	}
+}
+
+func newSummaryPayload(key *SummaryKey, value *SummaryValue) EventPayload {
+	tcpEvent := TcpEvent{
+		Comm:    value.Comm,
+		Pid:     key.Pid,
+		Uid:     value.Uid,
+		Dport:   key.Dport,
+		Af:      uint16(key.Af),
+		TaskIDs: value.TaskIDs,
+	}
+
+	// Connects of other families from the tracepoint program have no
+	// destination.
+	eventPayload := newGenericTcpEventPayload(&tcpEvent)
+	switch tcpEvent.Af {
+	case conv.AF_INET:
+		eventPayload.DestIP = conv.ToIP4(binary.LittleEndian.Uint32(key.Daddr[:4]))
+		eventPayload.DestPort = key.Dport
+	case conv.AF_INET6:
+		eventPayload.DestIP = conv.ToIP6(binary.LittleEndian.Uint64(key.Daddr[:8]), binary.LittleEndian.Uint64(key.Daddr[8:]))
+		eventPayload.DestPort = key.Dport
+	}
+
+	eventPayload.Type = TypeSummary
+	eventPayload.Protocol = ""
+	if key.Proto == protoTCP {
+		eventPayload.Protocol = ProtocolTCP
+	}
+	eventPayload.Result = ""
+	eventPayload.Errno = 0
+	eventPayload.CgroupID = key.CgroupId
+	eventPayload.Summary = &Summary{
+		Count:     value.Count,
+		Failed:    value.Failed,
+		FirstSeen: ktimeToTime(value.FirstNs / 1000),
+		LastSeen:  ktimeToTime(value.LastNs / 1000),
+	}
+	return eventPayload
+}
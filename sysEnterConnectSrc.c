@@ -1,22 +1,22 @@
 // +build ignore
 
-#include "vmlinux_compact_common.h"
+#include "common.h"
+#include "vmlinux_core.h"
 
-#if defined(__TARGET_ARCH_arm64)
-#include "vmlinux_compact_arm64.h"
-#elif defined(__TARGET_ARCH_x86)
-#include "vmlinux_compact_amd64.h"
-#endif
-
-#include "bpf_helpers.h"
-#include "bpf_tracing.h"
 #include "bpf_endian.h"
+#include "exclude.h"
+// TASK_IDS_CORE is set by the tp object only, tpnocore has no relocations
+// and loads on kernels without BTF.
+#include "task_ids.h"
+#include "scope.h"
+#include "summary.h"
 
 #define TASK_COMM_LEN 16
 #define AF_UNIX 1
 #define AF_UNSPEC 0
 #define AF_INET 2
 #define AF_INET6 10
+#define IPPROTO_TCP 6
 
 struct ipv4_event_t {
     u64 ts_us;
@@ -24,6 +24,8 @@ struct ipv4_event_t {
     u32 uid;
     u16 af;
     char task[TASK_COMM_LEN];
+    s32 ret;
+    struct task_ids ids;
     u32 daddr;
     u16 dport;
     u16 pad;
@@ -40,6 +42,8 @@ struct ipv6_event_t {
     u32 uid;
     u16 af;
     char task[TASK_COMM_LEN];
+    s32 ret;
+    struct task_ids ids;
     unsigned __int128 daddr;
     u16 dport;
     u16 pad;
@@ -56,6 +60,8 @@ struct other_socket_event_t {
     u32 uid;
     u16 af;
     char task[TASK_COMM_LEN];
+    s32 ret;
+    struct task_ids ids;
     u16 pad;
 } __attribute__((packed));
 
@@ -64,26 +70,42 @@ struct {
     __uint(max_entries, 1024);
 } other_socket_events SEC(".maps");
 
-struct sys_enter_connect_args {
-    __u64 pad[2];        
-    __u64 sockfd;      
-    const struct sockaddr *addr; 
-    __u64 addrlen; 
-};
+// connects holds the sockaddr passed to connect(), keyed by pid_tgid, until
+// sys_exit_connect reports the outcome.
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 10240);
+    __type(key, u64);
+    __type(value, struct sockaddr *);
+} connects SEC(".maps");
 
 SEC("tracepoint/syscalls/sys_enter_connect")
-int TcpConnect(struct sys_enter_connect_args *ctx) {
-    if (!ctx || !ctx->addr)
+int TcpConnect(struct trace_event_raw_sys_enter *ctx) {
+    // connect(int sockfd, const struct sockaddr *addr, socklen_t addrlen)
+    struct sockaddr *address = (struct sockaddr *)ctx->args[1];
+    if (!address || !in_scope())
         return 0;
 
     u64 pid_tgid = bpf_get_current_pid_tgid();
+
+    bpf_map_update_elem(&connects, &pid_tgid, &address, BPF_ANY);
+    return 0;
+}
+
+SEC("tracepoint/syscalls/sys_exit_connect")
+int TcpConnectExit(struct trace_event_raw_sys_exit *ctx) {
+    u64 pid_tgid = bpf_get_current_pid_tgid();
     u32 pid = pid_tgid >> 32;
     u32 uid = bpf_get_current_uid_gid();
 
-    struct sockaddr *address;
-    if (bpf_probe_read(&address, sizeof(address), &ctx->addr) < 0)
+    struct sockaddr **addressp = bpf_map_lookup_elem(&connects, &pid_tgid);
+    if (!addressp)
         return 0;
 
+    struct sockaddr *address = *addressp;
+    bpf_map_delete_elem(&connects, &pid_tgid);
+    s32 ret = ctx->ret;
+
     u16 address_family = 0;
     if (bpf_probe_read(&address_family, sizeof(address_family), &address->sa_family) < 0)
         return 0;
@@ -93,6 +115,8 @@ int TcpConnect(struct sys_enter_connect_args *ctx) {
         data4.pid = pid;
         data4.uid = uid;
         data4.af = address_family;
+        data4.ret = ret;
+        data4.ids = current_task_ids();
         data4.ts_us = bpf_ktime_get_ns() / 1000;
 
         struct sockaddr_in *daddr = (struct sockaddr_in *)address;
@@ -105,7 +129,13 @@ int TcpConnect(struct sys_enter_connect_args *ctx) {
 
         data4.dport = bpf_ntohs(dport);
         bpf_get_current_comm(&data4.task, sizeof(data4.task));
-        if (data4.dport != 0) {
+        if (data4.dport == 0 || is_excluded(AF_INET, data4.daddr, 0, data4.dport))
+            return 0;
+
+        if (summary_mode()) {
+            struct task_ids ids = data4.ids;
+            summary_add(AF_INET, data4.daddr, 0, data4.dport, IPPROTO_TCP, pid, uid, (u8 *)data4.task, &ids, ret);
+        } else {
             bpf_perf_event_output(ctx, &ipv4_events, BPF_F_CURRENT_CPU, &data4, sizeof(data4));
         }
     }
@@ -114,10 +144,12 @@ int TcpConnect(struct sys_enter_connect_args *ctx) {
         data6.pid = pid;
         data6.uid = uid;
         data6.af = address_family;
+        data6.ret = ret;
+        data6.ids = current_task_ids();
         data6.ts_us = bpf_ktime_get_ns() / 1000;
 
         struct sockaddr_in6 *daddr6 = (struct sockaddr_in6 *)address;
-        if (bpf_probe_read(&data6.daddr, sizeof(data6.daddr), &daddr6->sin6_addr.in6_u.u6_addr32) < 0)
+        if (bpf_probe_read(&data6.daddr, sizeof(data6.daddr), &daddr6->sin6_addr) < 0)
             return 0;
 
         u16 dport6 = 0;
@@ -127,7 +159,16 @@ int TcpConnect(struct sys_enter_connect_args *ctx) {
         data6.dport = bpf_ntohs(dport6);
         bpf_get_current_comm(&data6.task, sizeof(data6.task));
 
-        if (data6.dport != 0) {
+        // data6 is packed, copy the address out before taking its address.
+        __u8 daddr6_bytes[16];
+        __builtin_memcpy(daddr6_bytes, &data6.daddr, 16);
+        if (data6.dport == 0 || is_excluded(AF_INET6, 0, daddr6_bytes, data6.dport))
+            return 0;
+
+        if (summary_mode()) {
+            struct task_ids ids = data6.ids;
+            summary_add(AF_INET6, 0, daddr6_bytes, data6.dport, IPPROTO_TCP, pid, uid, (u8 *)data6.task, &ids, ret);
+        } else {
             bpf_perf_event_output(ctx, &ipv6_events, BPF_F_CURRENT_CPU, &data6, sizeof(data6));
         }
     }
@@ -136,9 +177,16 @@ int TcpConnect(struct sys_enter_connect_args *ctx) {
         socket_event.pid = pid;
         socket_event.uid = uid;
         socket_event.af = address_family;
+        socket_event.ret = ret;
+        socket_event.ids = current_task_ids();
         socket_event.ts_us = bpf_ktime_get_ns() / 1000;
         bpf_get_current_comm(&socket_event.task, sizeof(socket_event.task));
-        bpf_perf_event_output(ctx, &other_socket_events, BPF_F_CURRENT_CPU, &socket_event, sizeof(socket_event));
+        if (summary_mode()) {
+            struct task_ids ids = socket_event.ids;
+            summary_add(address_family, 0, 0, 0, 0, pid, uid, (u8 *)socket_event.task, &ids, ret);
+        } else {
+            bpf_perf_event_output(ctx, &other_socket_events, BPF_F_CURRENT_CPU, &socket_event, sizeof(socket_event));
+        }
     }
 
     return 0;
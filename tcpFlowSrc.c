@@ -0,0 +1,208 @@
+// +build ignore
+
+#include "vmlinux_compact_common.h"
+
+#if defined(__TARGET_ARCH_arm64)
+#include "vmlinux_compact_arm64.h"
+#elif defined(__TARGET_ARCH_x86)
+#include "vmlinux_compact_amd64.h"
+#endif
+
+#include "bpf_helpers.h"
+#include "bpf_tracing.h"
+#include "exclude.h"
+#define TASK_IDS_CORE
+#include "task_ids.h"
+
+#define TASK_COMM_LEN 16
+#define AF_INET 2
+#define AF_INET6 10
+#define IPPROTO_TCP 6
+
+#define TCP_ESTABLISHED 1
+#define TCP_SYN_SENT 2
+#define TCP_CLOSE 7
+
+char LICENSE[] SEC("license") = "Dual MIT/GPL";
+
+/**
+ * A flow is tracked from the moment a socket reaches ESTABLISHED until it
+ * moves to CLOSE, when a flow_event with its byte and packet counters is
+ * emitted. Bytes are counted on the return of tcp_sendmsg and tcp_recvmsg,
+ * packets are the segs_out/segs_in counters of the tcp_sock.
+ *
+ * struct flow_event starts with the same layout as struct event in
+ * fentryTcpConnectSrc.c.
+ */
+struct flow_event {
+    u8 comm[TASK_COMM_LEN];
+    __u32 pid;
+    __u32 uid;
+    __u16 sport;
+    __u16 dport;
+    __u16 af;
+    __u16 inbound;
+    __be32 saddr;
+    __be32 daddr;
+    __u64 ts_us;
+    __u8 saddr6[16];
+    __u8 daddr6[16];
+    struct task_ids ids;
+    __u64 start_us;
+    __u64 tx_bytes;
+    __u64 rx_bytes;
+    __u32 tx_packets;
+    __u32 rx_packets;
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+} flow_events SEC(".maps");
+
+// flows holds the flow of every tracked socket, keyed by the struct sock address.
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, 65536);
+    __type(key, u64);
+    __type(value, struct flow_event);
+} flows SEC(".maps");
+
+// sockets keeps the struct sock passed to tcp_sendmsg/tcp_recvmsg, keyed by
+// pid_tgid, until the matching kretprobe fires.
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 10240);
+    __type(key, u64);
+    __type(value, u64);
+} sockets SEC(".maps");
+
+static __always_inline void set_owner(struct flow_event *flow) {
+    flow->pid = bpf_get_current_pid_tgid() >> 32;
+    flow->uid = (u32)bpf_get_current_uid_gid();
+    bpf_get_current_comm(&flow->comm, TASK_COMM_LEN);
+    flow->ids = current_task_ids();
+}
+
+SEC("tracepoint/sock/inet_sock_set_state")
+int flow_set_state(struct trace_event_raw_inet_sock_set_state *ctx) {
+    if (ctx->protocol != IPPROTO_TCP) {
+        return 0;
+    }
+
+    u64 skaddr = (u64)ctx->skaddr;
+    if (ctx->newstate == TCP_SYN_SENT) {
+        // connect() runs in the context of the owning process.
+        struct flow_event flow = {};
+        set_owner(&flow);
+        bpf_map_update_elem(&flows, &skaddr, &flow, BPF_ANY);
+        return 0;
+    }
+
+    if (ctx->newstate == TCP_ESTABLISHED) {
+        struct flow_event *flow = bpf_map_lookup_elem(&flows, &skaddr);
+        struct flow_event inbound = {};
+        if (!flow) {
+            // Sockets created by a listener never went through SYN_SENT,
+            // the owner is set by the first send or receive.
+            inbound.inbound = 1;
+            bpf_map_update_elem(&flows, &skaddr, &inbound, BPF_ANY);
+            flow = bpf_map_lookup_elem(&flows, &skaddr);
+            if (!flow) {
+                return 0;
+            }
+        }
+        flow->start_us = bpf_ktime_get_ns() / 1000;
+        flow->af = ctx->family;
+        flow->sport = ctx->sport;
+        flow->dport = ctx->dport;
+        if (ctx->family == AF_INET) {
+            __builtin_memcpy(&flow->saddr, ctx->saddr, 4);
+            __builtin_memcpy(&flow->daddr, ctx->daddr, 4);
+        } else {
+            __builtin_memcpy(flow->saddr6, ctx->saddr_v6, 16);
+            __builtin_memcpy(flow->daddr6, ctx->daddr_v6, 16);
+        }
+        // Inbound flows are reported with the peer as the source, like
+        // accept events, so the local address is the destination.
+        int excluded = flow->inbound ? is_excluded(flow->af, flow->saddr, flow->saddr6, flow->sport)
+                                     : is_excluded(flow->af, flow->daddr, flow->daddr6, flow->dport);
+        if (excluded) {
+            bpf_map_delete_elem(&flows, &skaddr);
+        }
+        return 0;
+    }
+
+    if (ctx->newstate != TCP_CLOSE) {
+        return 0;
+    }
+
+    struct flow_event *flow = bpf_map_lookup_elem(&flows, &skaddr);
+    if (!flow) {
+        return 0;
+    }
+    if (flow->start_us) {
+        struct tcp_sock *tp = (struct tcp_sock *)ctx->skaddr;
+        bpf_probe_read(&flow->tx_packets, sizeof(flow->tx_packets), &tp->segs_out);
+        bpf_probe_read(&flow->rx_packets, sizeof(flow->rx_packets), &tp->segs_in);
+        flow->ts_us = bpf_ktime_get_ns() / 1000;
+        bpf_perf_event_output(ctx, &flow_events, BPF_F_CURRENT_CPU, flow, sizeof(*flow));
+    }
+    bpf_map_delete_elem(&flows, &skaddr);
+    return 0;
+}
+
+static __always_inline int trace_io_entry(struct sock *sk) {
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    u64 skaddr = (u64)sk;
+    bpf_map_update_elem(&sockets, &pid_tgid, &skaddr, BPF_ANY);
+    return 0;
+}
+
+static __always_inline int trace_io_return(int ret, int tx) {
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    u64 *skaddr = bpf_map_lookup_elem(&sockets, &pid_tgid);
+    if (!skaddr) {
+        return 0;
+    }
+    struct flow_event *flow = bpf_map_lookup_elem(&flows, skaddr);
+    bpf_map_delete_elem(&sockets, &pid_tgid);
+    if (!flow || ret <= 0) {
+        return 0;
+    }
+
+    if (!flow->pid) {
+        set_owner(flow);
+    }
+    if (tx) {
+        __sync_fetch_and_add(&flow->tx_bytes, ret);
+    } else {
+        __sync_fetch_and_add(&flow->rx_bytes, ret);
+    }
+    return 0;
+}
+
+SEC("kprobe/tcp_sendmsg")
+int BPF_KPROBE(tcp_sendmsg, struct sock *sk) {
+    return trace_io_entry(sk);
+}
+
+SEC("kretprobe/tcp_sendmsg")
+int BPF_KRETPROBE(tcp_sendmsg_ret, int ret) {
+    return trace_io_return(ret, 1);
+}
+
+/**
+ * tcp_recvmsg is used instead of tcp_cleanup_rbuf: newer kernels call
+ * __tcp_cleanup_rbuf from the receive path, which cannot be probed reliably.
+ */
+SEC("kprobe/tcp_recvmsg")
+int BPF_KPROBE(tcp_recvmsg, struct sock *sk) {
+    return trace_io_entry(sk);
+}
+
+SEC("kretprobe/tcp_recvmsg")
+int BPF_KRETPROBE(tcp_recvmsg_ret, int ret) {
+    return trace_io_return(ret, 0);
+}
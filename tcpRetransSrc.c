@@ -0,0 +1,239 @@
+// +build ignore
+
+#include "common.h"
+#include "vmlinux_core.h"
+
+// common.h only declares the x86 registers.
+#if defined(__TARGET_ARCH_arm64)
+#include "vmlinux_compact_arm64.h"
+#endif
+
+#include "bpf_tracing.h"
+#include "bpf_endian.h"
+#include "bpf_core_read.h"
+#include "exclude.h"
+#include "task_ids.h"
+
+#define TASK_COMM_LEN 16
+#define AF_INET 2
+#define AF_INET6 10
+#define IPPROTO_TCP 6
+
+#define TCP_SYN_SENT 2
+#define TCP_CLOSE 7
+
+#define KIND_RETRANSMIT 1
+#define KIND_SEND_RESET 2
+#define KIND_RECEIVE_RESET 3
+
+char LICENSE[] SEC("license") = "Dual MIT/GPL";
+
+/**
+ * rate_limit_ns is rewritten by userspace before loading. Each (kind, tuple)
+ * is reported at most once per window, the number of events dropped in
+ * between is carried by the next one in suppressed.
+ */
+const volatile __u64 rate_limit_ns = 1000000000ULL;
+
+/**
+ * struct retrans_event starts with the same layout as struct event in
+ * fentryTcpConnectSrc.c.
+ */
+struct retrans_event {
+    u8 comm[TASK_COMM_LEN];
+    __u32 pid;
+    __u32 uid;
+    __u16 sport;
+    __u16 dport;
+    __u16 af;
+    __u16 pad;
+    __be32 saddr;
+    __be32 daddr;
+    __u64 ts_us;
+    __u8 saddr6[16];
+    __u8 daddr6[16];
+    struct task_ids ids;
+    __u32 kind;
+    __s32 state;
+    __u32 suppressed;
+    __u32 pad2;
+};
+
+struct owner {
+    u8 comm[TASK_COMM_LEN];
+    __u32 pid;
+    __u32 uid;
+    struct task_ids ids;
+};
+
+struct tuple_key {
+    __u32 kind;
+    __u16 sport;
+    __u16 dport;
+    __u16 af;
+    __u16 pad;
+    __u8 saddr[16];
+    __u8 daddr[16];
+};
+
+struct rate {
+    __u64 last_ns;
+    __u32 suppressed;
+    __u32 pad;
+};
+
+/**
+ * The tcp tracepoints changed their address fields across kernel versions,
+ * but skaddr always follows the common fields (and skbaddr, if present), so
+ * the addresses are read from the socket itself.
+ */
+struct tcp_event_sk_skb_args {
+    __u64 pad;
+    const void *skbaddr;
+    const void *skaddr;
+    int state;
+};
+
+struct tcp_event_sk_args {
+    __u64 pad;
+    const void *skaddr;
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+} retrans_events SEC(".maps");
+
+/**
+ * Retransmissions and resets happen in softirq or timer context, where the
+ * current task has nothing to do with the socket. sk_owner remembers the
+ * process that connected or last sent on the socket, keyed by the struct
+ * sock address.
+ */
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, 65536);
+    __type(key, u64);
+    __type(value, struct owner);
+} sk_owner SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, 65536);
+    __type(key, struct tuple_key);
+    __type(value, struct rate);
+} rates SEC(".maps");
+
+static __always_inline void save_owner(u64 skaddr) {
+    struct owner o = {};
+    o.pid = bpf_get_current_pid_tgid() >> 32;
+    o.uid = (u32)bpf_get_current_uid_gid();
+    bpf_get_current_comm(&o.comm, TASK_COMM_LEN);
+    o.ids = current_task_ids();
+    bpf_map_update_elem(&sk_owner, &skaddr, &o, BPF_ANY);
+}
+
+SEC("tracepoint/sock/inet_sock_set_state")
+int retrans_set_state(struct trace_event_raw_inet_sock_set_state *ctx) {
+    if (ctx->protocol != IPPROTO_TCP) {
+        return 0;
+    }
+
+    u64 skaddr = (u64)ctx->skaddr;
+    if (ctx->newstate == TCP_SYN_SENT) {
+        save_owner(skaddr);
+    } else if (ctx->newstate == TCP_CLOSE) {
+        bpf_map_delete_elem(&sk_owner, &skaddr);
+    }
+    return 0;
+}
+
+SEC("kprobe/tcp_sendmsg")
+int BPF_KPROBE(retrans_tcp_sendmsg, struct sock *sk) {
+    u64 skaddr = (u64)sk;
+    if (!bpf_map_lookup_elem(&sk_owner, &skaddr)) {
+        save_owner(skaddr);
+    }
+    return 0;
+}
+
+static __always_inline int trace_tcp_event(void *ctx, const void *sk, __u32 kind, int state) {
+    if (!sk) {
+        // Resets sent for packets without a socket belong to no process.
+        return 0;
+    }
+
+    struct sock *s = (struct sock *)sk;
+    struct retrans_event ev = {};
+    struct tuple_key key = {};
+    ev.kind = kind;
+    ev.state = state;
+    ev.af = BPF_CORE_READ(s, __sk_common.skc_family);
+    ev.sport = BPF_CORE_READ(s, __sk_common.skc_num);
+    ev.dport = bpf_ntohs(BPF_CORE_READ(s, __sk_common.skc_dport));
+    if (ev.af == AF_INET) {
+        ev.saddr = BPF_CORE_READ(s, __sk_common.skc_rcv_saddr);
+        ev.daddr = BPF_CORE_READ(s, __sk_common.skc_daddr);
+        __builtin_memcpy(key.saddr, &ev.saddr, 4);
+        __builtin_memcpy(key.daddr, &ev.daddr, 4);
+    } else if (ev.af == AF_INET6 && bpf_core_field_exists(s->__sk_common.skc_v6_daddr)) {
+        BPF_CORE_READ_INTO(&ev.saddr6, s, __sk_common.skc_v6_rcv_saddr.in6_u.u6_addr8);
+        BPF_CORE_READ_INTO(&ev.daddr6, s, __sk_common.skc_v6_daddr.in6_u.u6_addr8);
+        __builtin_memcpy(key.saddr, ev.saddr6, 16);
+        __builtin_memcpy(key.daddr, ev.daddr6, 16);
+    } else {
+        return 0;
+    }
+    if (is_excluded(ev.af, ev.daddr, ev.daddr6, ev.dport)) {
+        return 0;
+    }
+    key.kind = kind;
+    key.af = ev.af;
+    key.sport = ev.sport;
+    key.dport = ev.dport;
+
+    u64 now = bpf_ktime_get_ns();
+    struct rate *r = bpf_map_lookup_elem(&rates, &key);
+    if (r) {
+        if (now - r->last_ns < rate_limit_ns) {
+            __sync_fetch_and_add(&r->suppressed, 1);
+            return 0;
+        }
+        ev.suppressed = r->suppressed;
+        r->suppressed = 0;
+        r->last_ns = now;
+    } else {
+        struct rate nr = {};
+        nr.last_ns = now;
+        bpf_map_update_elem(&rates, &key, &nr, BPF_ANY);
+    }
+
+    u64 skaddr = (u64)sk;
+    struct owner *o = bpf_map_lookup_elem(&sk_owner, &skaddr);
+    if (o) {
+        ev.pid = o->pid;
+        ev.uid = o->uid;
+        __builtin_memcpy(ev.comm, o->comm, TASK_COMM_LEN);
+        ev.ids = o->ids;
+    }
+    ev.ts_us = now / 1000;
+
+    bpf_perf_event_output(ctx, &retrans_events, BPF_F_CURRENT_CPU, &ev, sizeof(ev));
+    return 0;
+}
+
+SEC("tracepoint/tcp/tcp_retransmit_skb")
+int tcp_retransmit_skb(struct tcp_event_sk_skb_args *ctx) {
+    return trace_tcp_event(ctx, ctx->skaddr, KIND_RETRANSMIT, ctx->state);
+}
+
+SEC("tracepoint/tcp/tcp_send_reset")
+int tcp_send_reset(struct tcp_event_sk_skb_args *ctx) {
+    return trace_tcp_event(ctx, ctx->skaddr, KIND_SEND_RESET, ctx->state);
+}
+
+SEC("tracepoint/tcp/tcp_receive_reset")
+int tcp_receive_reset(struct tcp_event_sk_args *ctx) {
+    return trace_tcp_event(ctx, ctx->skaddr, KIND_RECEIVE_RESET, 0);
+}
@@ -0,0 +1,142 @@
+// +build ignore
+
+#include "vmlinux_compact_common.h"
+#include "bpf_helpers.h"
+#include "exclude.h"
+#include "task_ids.h"
+
+#define TASK_COMM_LEN 16
+#define AF_INET 2
+#define AF_INET6 10
+#define IPPROTO_TCP 6
+
+#define TCP_SYN_SENT 2
+#define TCP_SYN_RECV 3
+#define TCP_CLOSE 7
+#define TCP_LISTEN 10
+#define TCP_NEW_SYN_RECV 12
+
+#define DIRECTION_UNKNOWN 0
+#define DIRECTION_OUTBOUND 1
+#define DIRECTION_INBOUND 2
+
+char LICENSE[] SEC("license") = "Dual MIT/GPL";
+
+/**
+ * struct state_event starts with the same layout as struct event in
+ * fentryTcpConnectSrc.c and carries the old and new TCP state instead of
+ * the connect() return code. direction is one of DIRECTION_*.
+ */
+struct state_event {
+    u8 comm[TASK_COMM_LEN];
+    __u32 pid;
+    __u32 uid;
+    __u16 sport;
+    __u16 dport;
+    __u16 af;
+    __u16 direction;
+    __be32 saddr;
+    __be32 daddr;
+    __u64 ts_us;
+    __u8 saddr6[16];
+    __u8 daddr6[16];
+    struct task_ids ids;
+    __s32 oldstate;
+    __s32 newstate;
+};
+
+struct owner {
+    u8 comm[TASK_COMM_LEN];
+    __u32 pid;
+    __u32 uid;
+    struct task_ids ids;
+    __u32 direction;
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+} state_events SEC(".maps");
+
+/**
+ * Most transitions after SYN_SENT happen in softirq context, where the
+ * current task has nothing to do with the socket. sk_owner remembers the
+ * process that moved the socket to SYN_SENT, keyed by the struct sock address.
+ * Sockets leaving SYN_RECV were accepted by a listener and are remembered
+ * as inbound, without a process.
+ */
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, 65536);
+    __type(key, u64);
+    __type(value, struct owner);
+} sk_owner SEC(".maps");
+
+SEC("tracepoint/sock/inet_sock_set_state")
+int inet_sock_set_state(struct trace_event_raw_inet_sock_set_state *ctx) {
+    if (ctx->protocol != IPPROTO_TCP) {
+        return 0;
+    }
+
+    // Listeners have no peer.
+    if (ctx->oldstate == TCP_LISTEN || ctx->newstate == TCP_LISTEN) {
+        return 0;
+    }
+
+    u64 skaddr = (u64)ctx->skaddr;
+    if (ctx->newstate == TCP_SYN_SENT) {
+        struct owner o = {};
+        o.pid = bpf_get_current_pid_tgid() >> 32;
+        o.uid = (u32)bpf_get_current_uid_gid();
+        bpf_get_current_comm(&o.comm, TASK_COMM_LEN);
+        o.ids = current_task_ids();
+        o.direction = DIRECTION_OUTBOUND;
+        bpf_map_update_elem(&sk_owner, &skaddr, &o, BPF_ANY);
+    } else if (ctx->oldstate == TCP_SYN_RECV || ctx->oldstate == TCP_NEW_SYN_RECV) {
+        struct owner o = {};
+        o.direction = DIRECTION_INBOUND;
+        bpf_map_update_elem(&sk_owner, &skaddr, &o, BPF_NOEXIST);
+    }
+
+    // Sockets without an entry, e.g. evicted from the LRU or created before
+    // lightmon started, are reported with an unknown direction.
+    struct state_event ev = {};
+    struct owner *o = bpf_map_lookup_elem(&sk_owner, &skaddr);
+    if (o) {
+        ev.pid = o->pid;
+        ev.uid = o->uid;
+        __builtin_memcpy(ev.comm, o->comm, TASK_COMM_LEN);
+        ev.ids = o->ids;
+        ev.direction = o->direction;
+    }
+
+    ev.ts_us = bpf_ktime_get_ns() / 1000;
+    ev.af = ctx->family;
+    ev.sport = ctx->sport;
+    ev.dport = ctx->dport;
+    ev.oldstate = ctx->oldstate;
+    ev.newstate = ctx->newstate;
+    if (ctx->family == AF_INET) {
+        __builtin_memcpy(&ev.saddr, ctx->saddr, 4);
+        __builtin_memcpy(&ev.daddr, ctx->daddr, 4);
+    } else if (ctx->family == AF_INET6) {
+        __builtin_memcpy(ev.saddr6, ctx->saddr_v6, 16);
+        __builtin_memcpy(ev.daddr6, ctx->daddr_v6, 16);
+    } else {
+        return 0;
+    }
+
+    // Accepted sockets are reported with the peer as the source, like
+    // accept events.
+    int excluded = ev.direction == DIRECTION_INBOUND ? is_excluded(ev.af, ev.saddr, ev.saddr6, ev.sport)
+                                                     : is_excluded(ev.af, ev.daddr, ev.daddr6, ev.dport);
+    if (!excluded) {
+        bpf_perf_event_output(ctx, &state_events, BPF_F_CURRENT_CPU, &ev, sizeof(ev));
+    }
+
+    if (ctx->newstate == TCP_CLOSE) {
+        bpf_map_delete_elem(&sk_owner, &skaddr);
+    }
+    return 0;
+}
@@ -0,0 +1,150 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/gotoolkits/lightmon/conv"
+	. "github.com/gotoolkits/lightmon/event"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 tcpretrans tcpRetransSrc.c -- -Iheaders/
+
+// retransmit event kinds, see tcpRetransSrc.c
+const (
+	kindRetransmit   = 1
+	kindSendReset    = 2
+	kindReceiveReset = 3
+)
+
+func setupBpfTcpRetransWorkers() {
+	if !TcpRetrans_Runtime_Verifier() {
+		log.Fatalln("tcp tracepoints are not supported")
+	}
+	if !haveKernelTypes() {
+		log.Fatalf("tcp retransmit events need kernel BTF, %s not found, use -btf to load it from a file", KERNEL_BTF)
+	}
+
+	spec, err := loadTcpretrans()
+	if err != nil {
+		log.Fatalf("loading tcp retransmit spec: %v", err)
+	}
+
+	window := time.Duration(config.TcpRetransWindow) * time.Second
+	if err := spec.Variables["rate_limit_ns"].Set(uint64(window.Nanoseconds())); err != nil {
+		log.Fatalf("setting tcp retransmit rate limit: %v", err)
+	}
+
+	// Load pre-compiled programs and maps into the kernel.
+	objs := tcpretransObjects{}
+	if err := spec.LoadAndAssign(&objs, collectionOptions()); err != nil {
+		log.Fatalf("loading tcp retransmit objects: %v", err)
+	}
+	addCloser(&objs)
+	loadKernelExclusions(objs.ExcludedPorts, objs.ExcludedNets)
+
+	tracepoints := []struct {
+		group string
+		name  string
+		prog  *ebpf.Program
+	}{
+		{"sock", "inet_sock_set_state", objs.RetransSetState},
+		{"tcp", "tcp_retransmit_skb", objs.TcpRetransmitSkb},
+		{"tcp", "tcp_send_reset", objs.TcpSendReset},
+		{"tcp", "tcp_receive_reset", objs.TcpReceiveReset},
+	}
+	for _, t := range tracepoints {
+		tp, err := link.Tracepoint(t.group, t.name, t.prog, nil)
+		if err != nil {
+			log.Fatalf("attaching tracepoint %s: %s", t.name, err)
+		}
+		addCloser(tp)
+	}
+
+	kp, err := link.Kprobe("tcp_sendmsg", objs.RetransTcpSendmsg, nil)
+	if err != nil {
+		log.Fatalf("attaching kprobe tcp_sendmsg: %s", err)
+	}
+	addCloser(kp)
+
+	rd := newPerfReader("tcpretrans", objs.RetransEvents)
+
+	go (func() {
+		for {
+			if !readTcpRetransEvents(rd) {
+				return
+			}
+		}
+	})()
+}
+
+func readTcpRetransEvents(rd *perf.Reader) bool {
+	var event TcpRetransEvent
+	sample, ok := readPerfSample(rd, "tcpretrans")
+	if !ok {
+		return false
+	}
+	if sample == nil || !decodeSample("tcpretrans", sample, &event) {
+		return true
+	}
+
+	printEvent(newTcpRetransEventPayload(&event))
+	return true
+}
+
+func newTcpRetransEventPayload(event *TcpRetransEvent) EventPayload {
+	tcpEvent := TcpEvent{
+		Comm:   event.Comm,
+		Pid:    event.Pid,
+		Uid:    event.Uid,
+		Sport:  event.Sport,
+		Dport:  event.Dport,
+		Af:     event.Af,
+		Saddr:  event.Saddr,
+		Daddr:  event.Daddr,
+		TsUs:   event.TsUs,
+		Saddr6: event.Saddr6,
+		Daddr6: event.Daddr6,
+
+		TaskIDs: event.TaskIDs,
+	}
+
+	var eventPayload EventPayload
+	if event.Pid == 0 {
+		// The socket was never seen in process context.
+		eventPayload = EventPayload{
+			UTime:         time.Now(),
+			Protocol:      ProtocolTCP,
+			AddressFamily: conv.ToAddressFamily(int(event.Af)),
+			EbpfType:      ebpfType.String(),
+		}
+		setTcpEventAddrs(&eventPayload, &tcpEvent)
+	} else {
+		eventPayload = newTcpEventPayload(&tcpEvent)
+	}
+
+	// The addresses are those of the local socket, whichever side opened it.
+	eventPayload.Direction = ""
+	switch event.Kind {
+	case kindRetransmit:
+		eventPayload.Type = TypeRetransmit
+	case kindSendReset:
+		eventPayload.Type = TypeResetSent
+	case kindReceiveReset:
+		eventPayload.Type = TypeResetReceived
+	}
+	if event.State != 0 {
+		eventPayload.State = conv.ToTcpState(int(event.State))
+	}
+	eventPayload.Suppressed = event.Suppressed
+	eventPayload.Result = ""
+	eventPayload.Errno = 0
+	return eventPayload
+}
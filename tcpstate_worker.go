@@ -0,0 +1,106 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/gotoolkits/lightmon/conv"
+	. "github.com/gotoolkits/lightmon/event"
+
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 tcpstate tcpStateSrc.c -- -Iheaders/
+
+func setupBpfTcpStateWorkers() {
+	if !TcpState_Runtime_Verifier() {
+		log.Fatalln("sock:inet_sock_set_state tracepoint is not supported")
+	}
+
+	// Load pre-compiled programs and maps into the kernel.
+	objs := tcpstateObjects{}
+	if err := loadTcpstateObjects(&objs, collectionOptions()); err != nil {
+		log.Fatalf("loading tcp state objects: %v", err)
+	}
+	addCloser(&objs)
+	loadKernelExclusions(objs.ExcludedPorts, objs.ExcludedNets)
+
+	tp, err := link.Tracepoint("sock", "inet_sock_set_state", objs.InetSockSetState, nil)
+	if err != nil {
+		log.Fatalf("attaching tracepoint: %s", err)
+	}
+	addCloser(tp)
+
+	rd := newPerfReader("tcpstate", objs.StateEvents)
+
+	go (func() {
+		for {
+			if !readTcpStateEvents(rd) {
+				return
+			}
+		}
+	})()
+}
+
+func readTcpStateEvents(rd *perf.Reader) bool {
+	var event TcpStateEvent
+	sample, ok := readPerfSample(rd, "tcpstate")
+	if !ok {
+		return false
+	}
+	if sample == nil || !decodeSample("tcpstate", sample, &event) {
+		return true
+	}
+
+	printEvent(newTcpStateEventPayload(&event))
+	return true
+}
+
+func newTcpStateEventPayload(event *TcpStateEvent) EventPayload {
+	tcpEvent := TcpEvent{
+		Comm:   event.Comm,
+		Pid:    event.Pid,
+		Uid:    event.Uid,
+		Sport:  event.Sport,
+		Dport:  event.Dport,
+		Af:     event.Af,
+		Saddr:  event.Saddr,
+		Daddr:  event.Daddr,
+		TsUs:   event.TsUs,
+		Saddr6: event.Saddr6,
+		Daddr6: event.Daddr6,
+
+		TaskIDs: event.TaskIDs,
+	}
+	var eventPayload EventPayload
+	if event.Direction != StateDirectionOutbound {
+		// Accepted sockets have no owning process, nor have sockets whose
+		// owner was not seen moving them to SYN_SENT.
+		direction := DirectionUnknown
+		if event.Direction == StateDirectionInbound {
+			swapTcpEventAddrs(&tcpEvent)
+			direction = DirectionInbound
+		}
+		eventPayload = EventPayload{
+			UTime:         time.Now(),
+			Direction:     direction,
+			Protocol:      ProtocolTCP,
+			AddressFamily: conv.ToAddressFamily(int(event.Af)),
+			EbpfType:      ebpfType.String(),
+		}
+		setTcpEventAddrs(&eventPayload, &tcpEvent)
+	} else {
+		eventPayload = newTcpEventPayload(&tcpEvent)
+	}
+
+	eventPayload.Type = TypeState
+	eventPayload.OldState = conv.ToTcpState(int(event.OldState))
+	eventPayload.State = conv.ToTcpState(int(event.NewState))
+	eventPayload.Result = ""
+	eventPayload.Errno = 0
+	return eventPayload
+}
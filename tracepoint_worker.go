@@ -0,0 +1,147 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gotoolkits/lightmon/conv"
+	. "github.com/gotoolkits/lightmon/event"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/features"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror -DTASK_IDS_CORE" -target amd64,arm64 tp sysEnterConnectSrc.c -- -Iheaders/
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 tpnocore sysEnterConnectSrc.c -- -Iheaders/
+
+func setupBpfTPWorkers() {
+	if !TP_Runtime_Verifier() {
+		log.Fatalln("tracepoint program type is not supported")
+	}
+
+	err := features.HaveProgramType(ebpf.TracePoint)
+	if errors.Is(err, ebpf.ErrNotSupported) {
+		log.Fatalln("tracepoint program type is not supported")
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	// Only the task IDs are read with CO-RE. Without BTF the object built
+	// without them is loaded, and userspace reads them from /proc.
+	load := loadTp
+	if !haveKernelTypes() {
+		log.Printf("%s not found, parent PID, start time and namespaces of tracepoint events are read from /proc", KERNEL_BTF)
+		load = loadTpnocore
+	}
+	spec, err := load()
+	if err != nil {
+		log.Fatalf("loading tracepoint spec: %v", err)
+	}
+	setKernelScope(spec)
+	setSummarySpec(spec)
+
+	// Load pre-compiled programs and maps into the kernel.
+	objs := tpObjects{}
+	if err := spec.LoadAndAssign(&objs, collectionOptions()); err != nil {
+		log.Fatalf("loading tracepoint objects: %v", err)
+	}
+	addCloser(&objs)
+	loadKernelExclusions(objs.ExcludedPorts, objs.ExcludedNets)
+
+	// Load eBPF program
+	tp, err := link.Tracepoint("syscalls", "sys_enter_connect", objs.TcpConnect, nil)
+	if err != nil {
+		log.Fatalf("attaching tracepoint: %s", err)
+	}
+	addCloser(tp)
+
+	tpExit, err := link.Tracepoint("syscalls", "sys_exit_connect", objs.TcpConnectExit, nil)
+	if err != nil {
+		log.Fatalf("attaching tracepoint: %s", err)
+	}
+	addCloser(tpExit)
+
+	rd4 := newPerfReader("tp_ipv4", objs.Ipv4Events)
+	rd6 := newPerfReader("tp_ipv6", objs.Ipv6Events)
+	rdOther := newPerfReader("tp_other", objs.OtherSocketEvents)
+	startSummary("tp", objs.ConnSummary, objs.SummaryDrops)
+
+	go (func() {
+		for {
+			if !readIP4Events(rd4) {
+				return
+			}
+		}
+	})()
+
+	go (func() {
+		for {
+			if !readIP6Events(rd6) {
+				return
+			}
+		}
+	})()
+
+	go (func() {
+		for {
+			if !readOtherEvents(rdOther) {
+				return
+			}
+		}
+	})()
+}
+
+func readIP4Events(rd *perf.Reader) bool {
+	var event IP4Event
+	sample, ok := readPerfSample(rd, "tp_ipv4")
+	if !ok {
+		return false
+	}
+	if sample == nil || !decodeSample("tp_ipv4", sample, &event) {
+		return true
+	}
+
+	eventPayload := newGenericEventPayload(&event.Event)
+	eventPayload.DestIP = conv.ToIP4(event.Daddr)
+	eventPayload.DestPort = event.Dport
+	printEvent(eventPayload)
+	return true
+}
+
+func readIP6Events(rd *perf.Reader) bool {
+	var event IP6Event
+	sample, ok := readPerfSample(rd, "tp_ipv6")
+	if !ok {
+		return false
+	}
+	if sample == nil || !decodeSample("tp_ipv6", sample, &event) {
+		return true
+	}
+
+	eventPayload := newGenericEventPayload(&event.Event)
+	eventPayload.DestIP = conv.ToIP6(event.Daddr1, event.Daddr2)
+	eventPayload.DestPort = event.Dport
+	printEvent(eventPayload)
+	return true
+}
+
+func readOtherEvents(rd *perf.Reader) bool {
+	var event OtherSocketEvent
+	sample, ok := readPerfSample(rd, "tp_other")
+	if !ok {
+		return false
+	}
+	if sample == nil || !decodeSample("tp_other", sample, &event) {
+		return true
+	}
+
+	eventPayload := newGenericEventPayload(&event.Event)
+	printEvent(eventPayload)
+	return true
+}
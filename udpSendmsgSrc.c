@@ -0,0 +1,160 @@
+// +build ignore
+
+#include "common.h"
+#include "vmlinux_core.h"
+
+// common.h only declares the x86 registers.
+#if defined(__TARGET_ARCH_arm64)
+#include "vmlinux_compact_arm64.h"
+#endif
+
+#include "bpf_tracing.h"
+#include "bpf_endian.h"
+#include "bpf_core_read.h"
+#include "exclude.h"
+#include "task_ids.h"
+
+#define TASK_COMM_LEN 16
+#define AF_INET 2
+#define AF_INET6 10
+
+char LICENSE[] SEC("license") = "Dual MIT/GPL";
+
+/**
+ * dedup_window_ns is rewritten by userspace before loading. A send is only
+ * reported if the same pid has not sent on the same 5-tuple within the window.
+ */
+const volatile __u64 dedup_window_ns = 10000000000ULL;
+
+/**
+ * struct event has the same layout as the one in fentryTcpConnectSrc.c, so
+ * UDP sends are decoded into event.TcpEvent as well.
+ */
+struct event {
+    u8 comm[TASK_COMM_LEN];
+    __u32 pid;
+    __u32 uid;
+    __u16 sport;
+    __u16 dport;
+    __u16 af;
+    __u16 pad;
+    __be32 saddr;
+    __be32 daddr;
+    __u64 ts_us;
+    __u8 saddr6[16];
+    __u8 daddr6[16];
+    struct task_ids ids;
+    __s32 ret;
+    __u32 pad2;
+};
+
+struct flow_key {
+    __u32 pid;
+    __u16 sport;
+    __u16 dport;
+    __u16 af;
+    __u16 pad;
+    __u8 saddr[16];
+    __u8 daddr[16];
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+} udp_events SEC(".maps");
+
+// udp_seen maps a (pid, 5-tuple) to the time it was last reported.
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, 65536);
+    __type(key, struct flow_key);
+    __type(value, u64);
+} udp_seen SEC(".maps");
+
+static __always_inline int trace_udp_sendmsg(struct pt_regs *ctx, struct sock *sk, struct msghdr *msg) {
+    struct event udp_info = {};
+    udp_info.af = BPF_CORE_READ(sk, __sk_common.skc_family);
+    udp_info.sport = BPF_CORE_READ(sk, __sk_common.skc_num);
+
+    // Unconnected sockets pass the destination in msg_name, connected
+    // sockets leave it NULL and use the peer stored in the socket.
+    void *name = BPF_CORE_READ(msg, msg_name);
+    if (name) {
+        struct sockaddr_in6 sa = {};
+        if (bpf_probe_read(&sa, sizeof(sa), name) < 0) {
+            return 0;
+        }
+        if (sa.sin6_family == AF_INET) {
+            struct sockaddr_in *sin = (struct sockaddr_in *)&sa;
+            udp_info.dport = bpf_ntohs(sin->sin_port);
+            udp_info.daddr = sin->sin_addr.s_addr;
+        } else if (sa.sin6_family == AF_INET6) {
+            udp_info.dport = bpf_ntohs(sa.sin6_port);
+            __builtin_memcpy(udp_info.daddr6, sa.sin6_addr.in6_u.u6_addr8, 16);
+        } else {
+            return 0;
+        }
+        udp_info.af = sa.sin6_family;
+    } else {
+        udp_info.dport = bpf_ntohs(BPF_CORE_READ(sk, __sk_common.skc_dport));
+        udp_info.daddr = BPF_CORE_READ(sk, __sk_common.skc_daddr);
+        if (bpf_core_field_exists(sk->__sk_common.skc_v6_daddr)) {
+            BPF_CORE_READ_INTO(&udp_info.daddr6, sk, __sk_common.skc_v6_daddr.in6_u.u6_addr8);
+        }
+    }
+
+    if (udp_info.af == AF_INET) {
+        udp_info.saddr = BPF_CORE_READ(sk, __sk_common.skc_rcv_saddr);
+        __builtin_memset(udp_info.daddr6, 0, 16);
+    } else if (udp_info.af == AF_INET6 && bpf_core_field_exists(sk->__sk_common.skc_v6_rcv_saddr)) {
+        BPF_CORE_READ_INTO(&udp_info.saddr6, sk, __sk_common.skc_v6_rcv_saddr.in6_u.u6_addr8);
+    } else {
+        return 0;
+    }
+
+    if (is_excluded(udp_info.af, udp_info.daddr, udp_info.daddr6, udp_info.dport)) {
+        return 0;
+    }
+
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    udp_info.pid = pid_tgid >> 32;
+
+    struct flow_key key = {};
+    key.pid = udp_info.pid;
+    key.sport = udp_info.sport;
+    key.dport = udp_info.dport;
+    key.af = udp_info.af;
+    if (udp_info.af == AF_INET) {
+        __builtin_memcpy(key.saddr, &udp_info.saddr, 4);
+        __builtin_memcpy(key.daddr, &udp_info.daddr, 4);
+    } else {
+        __builtin_memcpy(key.saddr, udp_info.saddr6, 16);
+        __builtin_memcpy(key.daddr, udp_info.daddr6, 16);
+    }
+
+    u64 now = bpf_ktime_get_ns();
+    u64 *last = bpf_map_lookup_elem(&udp_seen, &key);
+    if (last && now - *last < dedup_window_ns) {
+        return 0;
+    }
+    bpf_map_update_elem(&udp_seen, &key, &now, BPF_ANY);
+
+    udp_info.ts_us = now / 1000;
+    udp_info.uid = (u32)bpf_get_current_uid_gid();
+    bpf_get_current_comm(&udp_info.comm, TASK_COMM_LEN);
+    udp_info.ids = current_task_ids();
+
+    bpf_perf_event_output(ctx, &udp_events, BPF_F_CURRENT_CPU, &udp_info, sizeof(udp_info));
+    return 0;
+}
+
+SEC("kprobe/udp_sendmsg")
+int BPF_KPROBE(udp_sendmsg, struct sock *sk, struct msghdr *msg) {
+    return trace_udp_sendmsg(ctx, sk, msg);
+}
+
+SEC("kprobe/udpv6_sendmsg")
+int BPF_KPROBE(udpv6_sendmsg, struct sock *sk, struct msghdr *msg) {
+    return trace_udp_sendmsg(ctx, sk, msg);
+}
@@ -0,0 +1,91 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"log"
+	"time"
+
+	. "github.com/gotoolkits/lightmon/event"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 udp udpSendmsgSrc.c -- -Iheaders/
+
+func setupBpfUdpWorkers() {
+	if !Kprobe_Runtime_Verifier("udp_sendmsg") {
+		log.Fatalln("udp_sendmsg is not available for kprobes")
+	}
+	if !haveKernelTypes() {
+		log.Fatalf("udp send events need kernel BTF, %s not found, use -btf to load it from a file", KERNEL_BTF)
+	}
+
+	spec, err := loadUdp()
+	if err != nil {
+		log.Fatalf("loading udp spec: %v", err)
+	}
+
+	window := time.Duration(config.UdpDedupWindow) * time.Second
+	if err := spec.Variables["dedup_window_ns"].Set(uint64(window.Nanoseconds())); err != nil {
+		log.Fatalf("setting udp dedup window: %v", err)
+	}
+
+	// Load pre-compiled programs and maps into the kernel.
+	objs := udpObjects{}
+	if err := spec.LoadAndAssign(&objs, collectionOptions()); err != nil {
+		log.Fatalf("loading udp objects: %v", err)
+	}
+	addCloser(&objs)
+	loadKernelExclusions(objs.ExcludedPorts, objs.ExcludedNets)
+
+	probes := []struct {
+		symbol string
+		prog   *ebpf.Program
+	}{
+		{"udp_sendmsg", objs.UdpSendmsg},
+		{"udpv6_sendmsg", objs.Udpv6Sendmsg},
+	}
+	for _, p := range probes {
+		kp, err := link.Kprobe(p.symbol, p.prog, nil)
+		if err != nil {
+			log.Fatalf("attaching kprobe %s: %s", p.symbol, err)
+		}
+		addCloser(kp)
+	}
+
+	rd := newPerfReader("udp", objs.UdpEvents)
+
+	go (func() {
+		for {
+			if !readUdpEvents(rd) {
+				return
+			}
+		}
+	})()
+}
+
+func readUdpEvents(rd *perf.Reader) bool {
+	var event TcpEvent
+	sample, ok := readPerfSample(rd, "udp")
+	if !ok {
+		return false
+	}
+	if sample == nil || !decodeSample("udp", sample, &event) {
+		return true
+	}
+
+	printEvent(newUdpEventPayload(&event))
+	return true
+}
+
+func newUdpEventPayload(event *TcpEvent) EventPayload {
+	eventPayload := newTcpEventPayload(event)
+	eventPayload.Type = TypeSend
+	eventPayload.Protocol = ProtocolUDP
+	eventPayload.Result = ""
+	return eventPayload
+}
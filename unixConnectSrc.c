@@ -0,0 +1,132 @@
+// +build ignore
+
+#include "vmlinux_compact_common.h"
+#include "bpf_helpers.h"
+#include "task_ids.h"
+
+#define TASK_COMM_LEN 16
+#define AF_UNIX 1
+#define UNIX_PATH_MAX 108
+
+char LICENSE[] SEC("license") = "Dual MIT/GPL";
+
+/**
+ * struct unix_event starts with the same layout as struct ipv4_event_t in
+ * sysEnterConnectSrc.c and carries the path of the socket instead of the
+ * destination address.
+ *
+ * Abstract sockets start with a NUL byte and are not NUL terminated, their
+ * name is the first path_len bytes of path.
+ */
+struct unix_event {
+    u64 ts_us;
+    u32 pid;
+    u32 uid;
+    u16 af;
+    char task[TASK_COMM_LEN];
+    s32 ret;
+    struct task_ids ids;
+    u32 path_len;
+    char path[UNIX_PATH_MAX];
+} __attribute__((packed));
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+} unix_events SEC(".maps");
+
+struct sys_enter_connect_args {
+    __u64 pad[2];
+    __u64 sockfd;
+    const struct sockaddr *addr;
+    __u64 addrlen;
+};
+
+struct sys_exit_connect_args {
+    __u64 pad[2];
+    long ret;
+};
+
+struct unix_connect {
+    const struct sockaddr *addr;
+    u64 addrlen;
+};
+
+// connects holds the sockaddr_un passed to connect(), keyed by pid_tgid,
+// until sys_exit_connect reports the outcome.
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 10240);
+    __type(key, u64);
+    __type(value, struct unix_connect);
+} unix_connects SEC(".maps");
+
+// unix_heap holds the event while it is built, it is too large for the stack.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, u32);
+    __type(value, struct unix_event);
+} unix_heap SEC(".maps");
+
+SEC("tracepoint/syscalls/sys_enter_connect")
+int UnixConnect(struct sys_enter_connect_args *ctx) {
+    u16 family = 0;
+    if (!ctx->addr || bpf_probe_read(&family, sizeof(family), &ctx->addr->sa_family) < 0)
+        return 0;
+    if (family != AF_UNIX)
+        return 0;
+
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    struct unix_connect c = {
+        .addr = ctx->addr,
+        .addrlen = ctx->addrlen,
+    };
+    bpf_map_update_elem(&unix_connects, &pid_tgid, &c, BPF_ANY);
+    return 0;
+}
+
+SEC("tracepoint/syscalls/sys_exit_connect")
+int UnixConnectExit(struct sys_exit_connect_args *ctx) {
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    struct unix_connect *c = bpf_map_lookup_elem(&unix_connects, &pid_tgid);
+    if (!c)
+        return 0;
+
+    const struct sockaddr *addr = c->addr;
+    u64 addrlen = c->addrlen;
+    bpf_map_delete_elem(&unix_connects, &pid_tgid);
+
+    u32 zero = 0;
+    struct unix_event *ev = bpf_map_lookup_elem(&unix_heap, &zero);
+    if (!ev)
+        return 0;
+
+    // addrlen includes sun_family.
+    u32 path_len = 0;
+    if (addrlen > sizeof(sa_family_t)) {
+        path_len = addrlen - sizeof(sa_family_t);
+    }
+    if (path_len > UNIX_PATH_MAX) {
+        path_len = UNIX_PATH_MAX;
+    }
+
+    // Only read what the caller passed, the address may end a mapping.
+    __builtin_memset(ev->path, 0, UNIX_PATH_MAX);
+    if (path_len > 0 && path_len <= UNIX_PATH_MAX &&
+        bpf_probe_read(ev->path, path_len, (const char *)addr + sizeof(sa_family_t)) < 0)
+        return 0;
+
+    ev->ts_us = bpf_ktime_get_ns() / 1000;
+    ev->pid = pid_tgid >> 32;
+    ev->uid = (u32)bpf_get_current_uid_gid();
+    ev->af = AF_UNIX;
+    ev->ret = ctx->ret;
+    ev->ids = current_task_ids();
+    ev->path_len = path_len;
+    bpf_get_current_comm(&ev->task, sizeof(ev->task));
+
+    bpf_perf_event_output(ctx, &unix_events, BPF_F_CURRENT_CPU, ev, sizeof(*ev));
+    return 0;
+}
@@ -0,0 +1,72 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"log"
+
+	"github.com/gotoolkits/lightmon/conv"
+	. "github.com/gotoolkits/lightmon/event"
+
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 unix unixConnectSrc.c -- -Iheaders/
+
+func setupBpfUnixWorkers() {
+	if !TP_Runtime_Verifier() {
+		log.Fatalln("syscalls tracepoints are not supported")
+	}
+
+	// Load pre-compiled programs and maps into the kernel.
+	objs := unixObjects{}
+	if err := loadUnixObjects(&objs, collectionOptions()); err != nil {
+		log.Fatalf("loading unix objects: %v", err)
+	}
+	addCloser(&objs)
+
+	tp, err := link.Tracepoint("syscalls", "sys_enter_connect", objs.UnixConnect, nil)
+	if err != nil {
+		log.Fatalf("attaching tracepoint: %s", err)
+	}
+	addCloser(tp)
+
+	tpExit, err := link.Tracepoint("syscalls", "sys_exit_connect", objs.UnixConnectExit, nil)
+	if err != nil {
+		log.Fatalf("attaching tracepoint: %s", err)
+	}
+	addCloser(tpExit)
+
+	rd := newPerfReader("unix", objs.UnixEvents)
+
+	go (func() {
+		for {
+			if !readUnixEvents(rd) {
+				return
+			}
+		}
+	})()
+}
+
+func readUnixEvents(rd *perf.Reader) bool {
+	var event UnixEvent
+	sample, ok := readPerfSample(rd, "unix")
+	if !ok {
+		return false
+	}
+	if sample == nil || !decodeSample("unix", sample, &event) {
+		return true
+	}
+
+	printEvent(newUnixEventPayload(&event))
+	return true
+}
+
+func newUnixEventPayload(event *UnixEvent) EventPayload {
+	eventPayload := newGenericEventPayload(&event.Event)
+	eventPayload.Protocol = ProtocolUnix
+	eventPayload.SocketPath = conv.ToUnixPath(event.Path[:], event.PathLen)
+	return eventPayload
+}